@@ -0,0 +1,94 @@
+package alertmanager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Statuses of alerts and alert groups
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Label that overrides receiver name when looking for a route
+const RouteLabel = "dutybot_route"
+
+const maxListedAlerts = 10
+
+// Alertmanager webhook payload. Detailed description is here:
+// https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
+type Message struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// Key used to find chat for this message.
+// Label dutybot_route takes precedence over receiver name.
+func (m Message) RouteKey() string {
+	if route, ok := m.CommonLabels[RouteLabel]; ok && route != "" {
+		return route
+	}
+	return m.Receiver
+}
+
+// Short human readable name of alert group
+func (m Message) Title() string {
+	if name, ok := m.CommonLabels["alertname"]; ok {
+		return name
+	}
+	if name, ok := m.GroupLabels["alertname"]; ok {
+		return name
+	}
+	return m.Receiver
+}
+
+// Describe alert using its annotations or labels
+func (a Alert) Summary() string {
+	for _, annotation := range []string{"summary", "description", "message"} {
+		if text, ok := a.Annotations[annotation]; ok && text != "" {
+			return text
+		}
+	}
+	if instance, ok := a.Labels["instance"]; ok {
+		return fmt.Sprintf("%s on %s", a.Labels["alertname"], instance)
+	}
+	return a.Labels["alertname"]
+}
+
+// Render message as plain text suitable for chat
+func (m Message) Format() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[%s:%d] %s\n", strings.ToUpper(m.Status), len(m.Alerts), m.Title())
+	for i, alert := range m.Alerts {
+		if i == maxListedAlerts {
+			fmt.Fprintf(&b, "... and %d more\n", len(m.Alerts)-maxListedAlerts)
+			break
+		}
+		fmt.Fprintf(&b, "- %s\n", alert.Summary())
+	}
+	if m.ExternalURL != "" {
+		fmt.Fprintf(&b, "%s\n", m.ExternalURL)
+	}
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package alertmanager
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteKey(t *testing.T) {
+	m := Message{Receiver: "team-a"}
+	assert.Equal(t, "team-a", m.RouteKey())
+
+	m.CommonLabels = map[string]string{RouteLabel: "team-b"}
+	assert.Equal(t, "team-b", m.RouteKey())
+}
+
+func TestFormat(t *testing.T) {
+	m := Message{
+		Status:       StatusFiring,
+		Receiver:     "team-a",
+		CommonLabels: map[string]string{"alertname": "HighLatency"},
+	}
+	for i := 0; i < maxListedAlerts+2; i++ {
+		m.Alerts = append(m.Alerts, Alert{
+			Labels: map[string]string{
+				"alertname": "HighLatency",
+				"instance":  fmt.Sprintf("host-%d", i),
+			},
+		})
+	}
+	m.Alerts[0].Annotations = map[string]string{"summary": "p99 is too high"}
+
+	text := m.Format()
+	assert.Contains(t, text, "[FIRING:12] HighLatency")
+	assert.Contains(t, text, "- p99 is too high")
+	assert.Contains(t, text, "- HighLatency on host-1")
+	assert.NotContains(t, text, "host-11")
+	assert.Contains(t, text, "... and 2 more")
+}
@@ -0,0 +1,234 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/absence"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Parse DD-MM-YYYY..DD-MM-YYYY or single DD-MM-YYYY of absence
+func parseAbsenceRange(raw string) (time.Time, time.Time, error) {
+	startsOn, endsOn, err := utils.ParseDateRange(raw)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if endsOn.Before(utils.GetToday()) {
+		return time.Time{}, time.Time{}, errors.New("absence is already over")
+	}
+	return startsOn, endsOn, nil
+}
+
+// Absence days without reason, safe to show to others
+func formatAbsenceDays(a absence.Absence) string {
+	text := a.StartsOn.Format(utils.HumanDateFormat)
+	if !a.EndsOn.Equal(a.StartsOn) {
+		text += " - " + a.EndsOn.Format(utils.HumanDateFormat)
+	}
+	return text
+}
+
+func formatAbsence(a absence.Absence) string {
+	text := formatAbsenceDays(a)
+	if a.Reason != "" {
+		text += fmt.Sprintf(" (%s)", a.Reason)
+	}
+	return text
+}
+
+// Absence of user conflicting with duty from start until end
+func findAbsence(ctx context.Context, userID int64, start, end time.Time) (absence.Absence, bool, error) {
+	absences, err := absence.AbsenceRepo.GetUserAbsences(ctx, userID, start)
+	if err != nil {
+		return absence.Absence{}, false, err
+	}
+	for _, a := range absences {
+		if a.Conflicts(start, end) {
+			return a, true, nil
+		}
+	}
+	return absence.Absence{}, false, nil
+}
+
+// Operator of either duty is away during the other one, so they can't be swapped.
+// Returns explanation for the users or empty string if swap is fine.
+func findSwapAbsence(ctx context.Context, first, second assignment.Assignment) (string, error) {
+	for _, pair := range [][2]assignment.Assignment{{first, second}, {second, first}} {
+		_, ok, err := findAbsence(ctx, pair[0].UserID, pair[1].StartsAt, pair[1].EndsAt)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return fmt.Sprintf("%s is away %s", pair[0].Operator, formatDutyTime(pair[1])), nil
+		}
+	}
+	return "", nil
+}
+
+// Operator of assignment is absent during it
+func isAway(absences []absence.Absence, as assignment.Assignment) bool {
+	for _, a := range absences {
+		if a.UserID == as.UserID && a.Conflicts(as.StartsAt, as.EndsAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// /away [DD-MM-YYYY..DD-MM-YYYY [reason] | cancel DD-MM-YYYY]
+func away(command Command) error {
+	args := strings.Fields(command.Arguments)
+	switch {
+	case len(args) == 0:
+		return listAbsences(command)
+	case args[0] == "cancel":
+		return cancelAbsence(command, strings.Join(args[1:], " "))
+	}
+
+	startsOn, endsOn, err := parseAbsenceRange(args[0])
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	a := absence.Absence{
+		ID:        uuid.New(),
+		UserID:    command.From.ID,
+		StartsOn:  startsOn,
+		EndsOn:    endsOn,
+		Reason:    strings.Join(args[1:], " "),
+		CreatedAt: time.Now().UTC(),
+	}
+	err = absence.AbsenceRepo.AddAbsence(context.Background(), a)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save absence", NoParseMode)
+		return err
+	}
+
+	reply(command, fmt.Sprintf("You are away %s", formatAbsence(a)), NoParseMode)
+	notifyAbsenceConflicts(a)
+	return nil
+}
+
+func listAbsences(command Command) error {
+	absences, err := absence.AbsenceRepo.GetUserAbsences(context.Background(), command.From.ID, utils.GetToday())
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch your absences", NoParseMode)
+		return err
+	}
+	if len(absences) == 0 {
+		reply(command, "You have no upcoming absences", NoParseMode)
+		return nil
+	}
+
+	lines := make([]string, 0, len(absences))
+	for _, a := range absences {
+		lines = append(lines, formatAbsence(a))
+	}
+	reply(command, "You are away:\n"+strings.Join(lines, "\n"), NoParseMode)
+	return nil
+}
+
+// Cancel absence covering given date
+func cancelAbsence(command Command, date string) error {
+	day, err := utils.ParseDate(date)
+	if err != nil {
+		reply(command, "Usage: /away cancel DD-MM-YYYY", NoParseMode)
+		return err
+	}
+
+	a, ok, err := findAbsence(context.Background(), command.From.ID, day, day.Add(utils.DayDuration))
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch your absences", NoParseMode)
+		return err
+	}
+	if !ok {
+		reply(command, "You are not away on that day", NoParseMode)
+		return nil
+	}
+
+	err = absence.AbsenceRepo.DeleteAbsence(context.Background(), a.ID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to cancel absence", NoParseMode)
+		return err
+	}
+	reply(command, fmt.Sprintf("Absence %s is cancelled", formatAbsence(a)), NoParseMode)
+	return nil
+}
+
+// Tell admins of every chat where absent user is already assigned.
+// Admins are told privately, chat gets the news without reason of absence
+// only if none of admins has started a private chat with bot.
+func notifyAbsenceConflicts(a absence.Absence) {
+	ctx := context.Background()
+	duties, err := assignment.AssignmentRepo.GetOperatorAssignments(ctx, a.UserID, a.StartsOn)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return
+	}
+
+	conflicts := make(map[int64][]assignment.Assignment)
+	chats := make([]int64, 0)
+	for _, as := range duties {
+		if !a.Conflicts(as.StartsAt, as.EndsAt) {
+			continue
+		}
+		if _, ok := conflicts[as.ChatID]; !ok {
+			chats = append(chats, as.ChatID)
+		}
+		conflicts[as.ChatID] = append(conflicts[as.ChatID], as)
+	}
+
+	for _, chatID := range chats {
+		duties := conflicts[chatID]
+		dates := make([]string, 0, len(duties))
+		for _, as := range duties {
+			dates = append(dates, formatDutyTime(as)+roleSuffix(as))
+		}
+		text := fmt.Sprintf(
+			"%s is away %s but assigned on:\n%s",
+			mentionOperator(ctx, duties[0]),
+			html.EscapeString(formatAbsenceDays(a)),
+			html.EscapeString(strings.Join(dates, "\n")),
+		)
+
+		admins, err := getChatAdmins(chatID)
+		if err != nil {
+			logger.Log.Warn().Err(err).Msg("Couldn't get chat admins")
+		}
+		notified := false
+		for _, admin := range admins {
+			msg := tgbot.NewMessage(
+				admin.ID,
+				fmt.Sprintf("%s: %s", html.EscapeString(getChatTitle(chatID)), text),
+			)
+			msg.ParseMode = HTMLParseMode
+			_, err := bot.Send(msg)
+			if err != nil {
+				logger.Log.Warn().
+					Err(err).
+					Int64("user_id", admin.ID).
+					Msg("Unable to notify chat admin")
+				continue
+			}
+			notified = true
+		}
+		if !notified {
+			announce(chatID, text, HTMLParseMode)
+		}
+	}
+}
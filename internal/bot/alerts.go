@@ -0,0 +1,237 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/alertmanager"
+	"github.com/FedoseevAlex/DutyBot/internal/database/alert"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/incident"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const maxAlertPayloadSize = 1 << 20
+
+var ErrNoAlertmanagerToken = errors.New("ALERTMANAGER_TOKEN is required when alertmanager receiver is enabled")
+
+func registerAlertmanagerHandler() error {
+	if !viper.GetBool("AlertmanagerEnabled") {
+		return nil
+	}
+	if viper.GetString("AlertmanagerToken") == "" {
+		return ErrNoAlertmanagerToken
+	}
+	http.HandleFunc(viper.GetString("AlertmanagerPath"), handleAlertmanager)
+	logger.Log.Info().
+		Str("path", viper.GetString("AlertmanagerPath")).
+		Msg("Alertmanager receiver enabled")
+	return nil
+}
+
+// In long poll mode there is no server for telegram hooks,
+// so alertmanager receiver needs its own one. It serves TLS if
+// certificate is given, plain HTTP is for local reverse proxy.
+func startAlertmanagerServer() {
+	if !viper.GetBool("AlertmanagerEnabled") {
+		return
+	}
+
+	address := viper.GetString("AlertmanagerListenAddress")
+	certPath := viper.GetString("AlertmanagerCertPath")
+	keyPath := viper.GetString("AlertmanagerKeyPath")
+	var err error
+	if certPath != "" && keyPath != "" {
+		err = http.ListenAndServeTLS(address, certPath, keyPath, nil)
+	} else {
+		if host, _, splitErr := net.SplitHostPort(address); splitErr != nil || !isLoopback(host) {
+			logger.Log.Warn().
+				Str("address", address).
+				Msg("Alertmanager receiver serves plain HTTP, token is sent unencrypted")
+		}
+		err = http.ListenAndServe(address, nil)
+	}
+	if err != nil {
+		logger.Log.Error().
+			Stack().
+			Err(err).
+			Msg("Alertmanager receiver stopped")
+	}
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func checkAlertmanagerToken(req *http.Request) bool {
+	token := viper.GetString("AlertmanagerToken")
+	if token == "" {
+		return false
+	}
+	given := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func handleAlertmanager(w http.ResponseWriter, req *http.Request) {
+	defer utils.Close(req.Body)
+
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !checkAlertmanagerToken(req) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var msg alertmanager.Message
+	err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxAlertPayloadSize)).Decode(&msg)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Msg("Unable to unmarshal alertmanager payload")
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+
+	err = postAlert(req.Context(), msg)
+	switch {
+	case errors.Is(err, alert.ErrNotFound):
+		logger.Log.Warn().
+			Str("route", msg.RouteKey()).
+			Msg("No chat for alertmanager route")
+		http.Error(w, "unknown route", http.StatusNotFound)
+	case err != nil:
+		logger.Log.Error().
+			Stack().
+			Err(err).
+			Msg("Unable to post alert")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func postAlert(ctx context.Context, msg alertmanager.Message) error {
+	route, err := alert.AlertRepo.GetRoute(ctx, msg.RouteKey())
+	if err != nil {
+		return err
+	}
+
+	inc, err := incident.IncidentRepo.GetIncidentByGroupKey(ctx, route.ChatID, msg.GroupKey)
+	switch {
+	case errors.Is(err, incident.ErrNotFound):
+	case err != nil:
+		return err
+	default:
+		if msg.Status == alertmanager.StatusResolved {
+			return resolveIncident(ctx, inc, "alertmanager", "alertmanager")
+		}
+		// Alertmanager repeats notifications for the same group,
+		// incident for them is already tracked
+		return nil
+	}
+
+	if msg.Status == alertmanager.StatusResolved {
+		announce(route.ChatID, msg.Format(), NoParseMode)
+		return nil
+	}
+
+	as, err := assignment.AssignmentRepo.GetAssignmentAt(ctx, utils.GetNow(), route.ChatID, assignment.RolePrimary)
+	if err != nil {
+		return err
+	}
+	return openIncident(ctx, route.ChatID, msg, as)
+}
+
+// Manage which alertmanager routes are posted to the chat
+func manageAlertRoutes(command Command) error {
+	args := strings.Fields(command.Arguments)
+	if len(args) == 0 {
+		return listAlertRoutes(command)
+	}
+
+	const routeArgs = 2
+	if len(args) != routeArgs || (args[0] != "route" && args[0] != "unroute") {
+		reply(command, "Usage: /alerts [route name | unroute name]", NoParseMode)
+		return nil
+	}
+
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can manage alert routes", NoParseMode)
+		return nil
+	}
+
+	if args[0] == "route" {
+		err = alert.AlertRepo.SetRoute(context.Background(), alert.Route{
+			Route:     args[1],
+			ChatID:    command.ChatID,
+			CreatedAt: utils.GetToday(),
+		})
+		switch {
+		case errors.Is(err, alert.ErrRouteTaken):
+			reply(command, fmt.Sprintf("Route '%s' belongs to another chat", args[1]), NoParseMode)
+			return nil
+		case err != nil:
+			logger.Log.Error().Stack().Err(err).Send()
+			reply(command, "Failed to save alert route", NoParseMode)
+			return err
+		default:
+		}
+		reply(command, fmt.Sprintf("Alerts for '%s' will be posted here", args[1]), NoParseMode)
+		return nil
+	}
+
+	err = alert.AlertRepo.DeleteRoute(context.Background(), command.ChatID, args[1])
+	switch {
+	case errors.Is(err, alert.ErrNotFound):
+		reply(command, fmt.Sprintf("No route '%s' in this chat", args[1]), NoParseMode)
+		return nil
+	case err != nil:
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to remove alert route", NoParseMode)
+		return err
+	default:
+	}
+	reply(command, fmt.Sprintf("Route '%s' removed", args[1]), NoParseMode)
+	return nil
+}
+
+func listAlertRoutes(command Command) error {
+	routes, err := alert.AlertRepo.GetRoutes(context.Background(), command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch alert routes", NoParseMode)
+		return err
+	}
+	if len(routes) == 0 {
+		reply(command, "No alert routes for this chat", NoParseMode)
+		return nil
+	}
+
+	names := make([]string, 0, len(routes))
+	for _, route := range routes {
+		names = append(names, route.Route)
+	}
+	reply(command, strings.Join(names, "\n"), NoParseMode)
+	return nil
+}
@@ -6,13 +6,27 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
+	"time"
 
 	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/spf13/viper"
 
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
 	"github.com/FedoseevAlex/DutyBot/internal/config"
+	"github.com/FedoseevAlex/DutyBot/internal/database/absence"
+	"github.com/FedoseevAlex/DutyBot/internal/database/alert"
 	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
+	"github.com/FedoseevAlex/DutyBot/internal/database/checkpoint"
+	"github.com/FedoseevAlex/DutyBot/internal/database/incident"
+	"github.com/FedoseevAlex/DutyBot/internal/database/pending"
+	"github.com/FedoseevAlex/DutyBot/internal/database/preference"
+	"github.com/FedoseevAlex/DutyBot/internal/database/recurrence"
+	"github.com/FedoseevAlex/DutyBot/internal/database/redemption"
+	"github.com/FedoseevAlex/DutyBot/internal/database/shift"
+	"github.com/FedoseevAlex/DutyBot/internal/database/user"
+	"github.com/FedoseevAlex/DutyBot/internal/database/watch"
+	"github.com/FedoseevAlex/DutyBot/internal/database/webhook"
 	"github.com/FedoseevAlex/DutyBot/internal/logger"
 	"github.com/FedoseevAlex/DutyBot/internal/tasks"
 	"github.com/FedoseevAlex/DutyBot/internal/utils"
@@ -20,10 +34,18 @@ import (
 
 var bot *tgbot.BotAPI
 
-func processUpdate(update tgbot.Update) error {
+func processUpdate(update tgbot.Update, threadID int) error {
 	var command Command
+	from := userFromTelegram(update.SentFrom())
+	if update.Message != nil || update.EditedMessage != nil || update.CallbackQuery != nil || update.InlineQuery != nil {
+		rememberUser(from)
+	}
+
 	switch {
 	case update.Message != nil:
+		if from, to, ok := getChatMigration(update.Message); ok {
+			return migrateChat(from, to)
+		}
 		if !update.Message.IsCommand() {
 			// Avoid non command messages (e.g. reply)
 			return nil
@@ -31,26 +53,22 @@ func processUpdate(update tgbot.Update) error {
 		command = Command{
 			Action:    update.Message.Command(),
 			Arguments: update.Message.CommandArguments(),
-			Operator:  update.SentFrom().UserName,
+			From:      from,
 			ChatID:    update.FromChat().ID,
+			ThreadID:  threadID,
 		}
 	case update.EditedMessage != nil:
 		command = Command{
 			Action:    update.EditedMessage.Command(),
 			Arguments: update.EditedMessage.CommandArguments(),
-			Operator:  update.SentFrom().UserName,
+			From:      from,
 			ChatID:    update.FromChat().ID,
+			ThreadID:  threadID,
 		}
 	case update.CallbackQuery != nil:
-		action, arguments, _ := strings.Cut(update.CallbackData(), " ")
-		command = Command{
-			Action:     action,
-			Arguments:  arguments,
-			Operator:   update.SentFrom().UserName,
-			ChatID:     update.FromChat().ID,
-			KeyboardID: update.CallbackQuery.Message.MessageID,
-		}
-		return processCallback(command)
+		return processCallbackQuery(update, from, threadID)
+	case update.InlineQuery != nil:
+		return processInlineQuery(update.InlineQuery)
 	default:
 		logger.Log.Info().Str("update", fmt.Sprintf("%+v", update)).Send()
 		return nil
@@ -63,6 +81,38 @@ func processUpdate(update tgbot.Update) error {
 	return nil
 }
 
+// Run pressed button and answer it, so telegram stops loading animation
+func processCallbackQuery(update tgbot.Update, from user.User, threadID int) error {
+	data, err := callback.Decode(update.CallbackData())
+	if err != nil {
+		answerCallback(update.CallbackQuery.ID, "This keyboard is outdated, please request a new one")
+		return err
+	}
+	command := Command{
+		Arguments:  getCallbackArguments(update.FromChat().ID, data),
+		From:       from,
+		ChatID:     update.FromChat().ID,
+		ThreadID:   threadID,
+		KeyboardID: update.CallbackQuery.Message.MessageID,
+		Callback:   data,
+	}
+	err = processCallback(command)
+	if err != nil {
+		answerCallback(update.CallbackQuery.ID, "Something went wrong")
+		return err
+	}
+	answerCallback(update.CallbackQuery.ID, "")
+	return nil
+}
+
+// Stop loading animation on pressed button showing optional text
+func answerCallback(queryID string, text string) {
+	_, err := bot.Request(tgbot.NewCallback(queryID, text))
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Unable to answer callback query")
+	}
+}
+
 func handleRequests(_ http.ResponseWriter, req *http.Request) {
 	defer utils.Close(req.Body)
 
@@ -86,7 +136,7 @@ func handleRequests(_ http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = processUpdate(update)
+	err = processUpdate(update, getThreadID(bodyBytes))
 	if err != nil {
 		logger.Log.Error().
 			Err(err).
@@ -106,6 +156,15 @@ func StartBot() error {
 		return err
 	}
 
+	err = registerAlertmanagerHandler()
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Stack().
+			Msg("Unable to start alertmanager receiver")
+		return err
+	}
+
 	if viper.GetBool("HookMode") {
 		err = StartBotHook()
 	} else {
@@ -156,21 +215,59 @@ func StartBotHook() error {
 }
 
 func StartBotLongPoll() error {
-	updateConfig := tgbot.UpdateConfig{}
-	updateConfig.Timeout = 5
+	go startAlertmanagerServer()
 
-	updates := bot.GetUpdatesChan(updateConfig)
-	for update := range updates {
-		err := processUpdate(update)
+	// Updates are fetched as raw json because tgbot.Update
+	// has no forum topic fields
+	const (
+		pollTimeout = 5
+		retryDelay  = 3 * time.Second
+	)
+	offset := 0
+	for {
+		updates, err := getUpdates(offset, pollTimeout)
 		if err != nil {
 			logger.Log.Error().
-				Stack().
 				Err(err).
-				Msg("Unable to process update")
+				Msg("Unable to get updates")
+			time.Sleep(retryDelay)
+			continue
 		}
-	}
 
-	return nil
+		for _, raw := range updates {
+			// Update ID is read on its own, so update that doesn't
+			// fit tgbot.Update is skipped instead of fetched forever
+			var header struct {
+				UpdateID int `json:"update_id"`
+			}
+			err = json.Unmarshal(raw, &header)
+			if err != nil {
+				logger.Log.Error().
+					Err(err).
+					Msg("Unable to get update ID from telegram update")
+				continue
+			}
+			offset = header.UpdateID + 1
+
+			var update tgbot.Update
+			err = json.Unmarshal(raw, &update)
+			if err != nil {
+				logger.Log.Error().
+					Err(err).
+					Int("update_id", header.UpdateID).
+					Msg("Unable to unmarshal json update from telegram")
+				continue
+			}
+
+			err = processUpdate(update, getThreadID(raw))
+			if err != nil {
+				logger.Log.Error().
+					Stack().
+					Err(err).
+					Msg("Unable to process update")
+			}
+		}
+	}
 }
 
 func scheduleAnnounceDutyTask() {
@@ -198,6 +295,45 @@ func scheduleFreeSlotsTask() {
 	}
 }
 
+// Init function of repo logging its failure
+func repoInit[R any](name string, initRepo func(context.Context, string) (R, error)) func() error {
+	return func() error {
+		_, err := initRepo(context.Background(), viper.GetString("DBConnectString"))
+		if err != nil {
+			logger.Log.Error().
+				Stack().
+				Err(err).
+				Msgf("failed go init %s repo", name)
+		}
+		return err
+	}
+}
+
+func initRepos() error {
+	inits := []func() error{
+		repoInit("assignment", assignment.InitAssignmentRepo),
+		repoInit("webhook", webhook.InitWebhookRepo),
+		repoInit("alert", alert.InitAlertRepo),
+		repoInit("incident", incident.InitIncidentRepo),
+		repoInit("user", user.InitUserRepo),
+		repoInit("chat", chat.InitChatRepo),
+		repoInit("absence", absence.InitAbsenceRepo),
+		repoInit("preference", preference.InitPreferenceRepo),
+		repoInit("recurrence", recurrence.InitRecurrenceRepo),
+		repoInit("shift", shift.InitShiftRepo),
+		repoInit("watch", watch.InitWatchRepo),
+		repoInit("pending", pending.InitPendingRepo),
+		repoInit("redemption", redemption.InitRedemptionRepo),
+		repoInit("checkpoint", checkpoint.InitCheckpointRepo),
+	}
+	for _, initRepo := range inits {
+		if err := initRepo(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func initBot() error {
 	if err := config.ReadConfig(); err != nil {
 		logger.Log.Error().
@@ -210,12 +346,8 @@ func initBot() error {
 	tasks.InitScheduler()
 	initHandlers()
 
-	_, err := assignment.InitAssignmentRepo(context.Background(), viper.GetString("DBConnectString"))
+	err := initRepos()
 	if err != nil {
-		logger.Log.Error().
-			Stack().
-			Err(err).
-			Msg("failed go init assignment repo")
 		return err
 	}
 
@@ -227,8 +359,13 @@ func initBot() error {
 			Msg("failed to create bot")
 		return err
 	}
+	registerCommandMenu()
 	scheduleAnnounceDutyTask()
 	scheduleFreeSlotsTask()
+	scheduleEscalationTask()
+	scheduleShiftsTask()
+	scheduleRecurrenceTask()
+	scheduleReportTask()
 	tasks.Start()
 	logger.Log.Debug().Msg("Starting dutybot...")
 	return nil
@@ -0,0 +1,427 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
+	"github.com/FedoseevAlex/DutyBot/internal/database/pending"
+	"github.com/FedoseevAlex/DutyBot/internal/database/recurrence"
+	"github.com/FedoseevAlex/DutyBot/internal/database/user"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Arguments of /reset asking for bulk reset
+func isBulkReset(arguments string) bool {
+	args := strings.Fields(arguments)
+	return len(args) != 0 && (strings.HasPrefix(args[0], "@") || args[0] == "range")
+}
+
+func lookupUsername(username string) (int64, error) {
+	u, err := user.UserRepo.GetUserByUsername(context.Background(), username)
+	if errors.Is(err, user.ErrNotFound) {
+		return 0, fmt.Errorf("I don't know @%s", username)
+	}
+	return u.ID, err
+}
+
+// Usage of bulk reset, returned when arguments don't match it
+var errResetUsage = errors.New(
+	"Usage: /reset @user [from DD-MM-YYYY] or /reset range DD-MM-YYYY" + utils.DateRangeSeparator + "DD-MM-YYYY",
+)
+
+// Build filter from arguments of /reset @user [from DD-MM-YYYY]
+// or /reset range A..B. Only days since today are reset.
+func parseResetFilter(chatID int64, args []string) (assignment.ResetFilter, error) {
+	today := utils.GetToday()
+	filter := assignment.ResetFilter{ChatID: chatID, From: today}
+	if len(args) == 0 {
+		return filter, errResetUsage
+	}
+
+	if args[0] == "range" {
+		if len(args) != 2 || !strings.Contains(args[1], utils.DateRangeSeparator) {
+			return filter, errResetUsage
+		}
+		from, to, err := utils.ParseDateRange(args[1])
+		if err != nil {
+			return filter, err
+		}
+		if from.Before(today) {
+			return filter, errors.New("past duties can't be reset")
+		}
+		filter.From, filter.To = from, to
+		return filter, nil
+	}
+
+	if !strings.HasPrefix(args[0], "@") || len(args) != 1 && (len(args) != 3 || args[1] != "from") {
+		return filter, errResetUsage
+	}
+	userID, err := lookupUsername(strings.TrimPrefix(args[0], "@"))
+	if err != nil {
+		return filter, err
+	}
+	filter.UserID = userID
+
+	if len(args) == 3 {
+		from, err := utils.ParseDate(args[2])
+		if err != nil {
+			return filter, err
+		}
+		if from.After(filter.From) {
+			filter.From = from
+		}
+	}
+	return filter, nil
+}
+
+func formatResetDuties(duties []assignment.Assignment) string {
+	lines := make([]string, 0, len(duties))
+	for _, as := range duties {
+		lines = append(lines, fmt.Sprintf("%s %s%s", formatDutyTime(as), as.Operator, roleSuffix(as)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Only previewed duties are reset on confirmation
+func addResetRequest(
+	command Command,
+	filter assignment.ResetFilter,
+	duties []assignment.Assignment,
+) (pending.Pending, error) {
+	ids := make([]uuid.UUID, 0, len(duties))
+	for _, as := range duties {
+		ids = append(ids, as.ID)
+	}
+	request, err := pending.NewPending(
+		command.ChatID,
+		command.From.ID,
+		pending.KindReset,
+		ids,
+		viper.GetDuration("ResetConfirmTimeout"),
+	)
+	if err != nil {
+		return request, err
+	}
+	request.SubjectID = filter.UserID
+	return request, pending.PendingRepo.AddPending(context.Background(), request)
+}
+
+// /reset @user [from DD-MM-YYYY] and /reset range A..B
+// show affected duties and ask admin to confirm
+func bulkReset(command Command) error {
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can reset duties of others", NoParseMode)
+		return nil
+	}
+
+	filter, err := parseResetFilter(command.ChatID, strings.Fields(command.Arguments))
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	duties, err := assignment.AssignmentRepo.GetAssignments(context.Background(), filter)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch duties", NoParseMode)
+		return err
+	}
+	if len(duties) == 0 {
+		reply(command, "Nothing to reset", NoParseMode)
+		return nil
+	}
+
+	request, err := addResetRequest(command, filter, duties)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't save reset request", NoParseMode)
+		return err
+	}
+	confirm := callback.Data{Action: callback.ActionReset, View: callback.ViewReset, Date: filter.From, ID: request.ID}
+	cancel := callback.Data{Action: callback.ActionCancel, View: callback.ViewReset, ID: request.ID}
+
+	msg := tgbot.NewMessage(
+		command.ChatID,
+		fmt.Sprintf("These duties will be reset:\n%s", formatResetDuties(duties)),
+	)
+	msg.ReplyMarkup = tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(
+		callbackButton(fmt.Sprintf("Reset %d", len(duties)), confirm),
+		callbackButton("Cancel", cancel),
+	))
+	_, err = send(msg, command.ThreadID)
+	return err
+}
+
+func processResetCallback(command Command) error {
+	switch command.Callback.Action {
+	case callback.ActionCancel:
+		return cancelReset(command)
+	case callback.ActionConfirm:
+		return confirmReset(command)
+	case callback.ActionReset:
+		return confirmBulkReset(command)
+	}
+	return fmt.Errorf("unknown reset callback %d", command.Callback.Action)
+}
+
+// Only the one who asked for reset can cancel it
+func cancelReset(command Command) error {
+	if command.Callback.ID != uuid.Nil {
+		ctx := context.Background()
+		request, err := pending.PendingRepo.GetPending(ctx, command.Callback.ID)
+		switch {
+		case errors.Is(err, pending.ErrNotFound):
+		case err != nil:
+			return err
+		case request.UserID != command.From.ID:
+			return nil
+		default:
+			err = pending.PendingRepo.DeletePending(ctx, request.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	editCallbackMessage(command, "Reset is cancelled", uuid.Nil)
+	return nil
+}
+
+func confirmBulkReset(command Command) error {
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return nil
+	}
+
+	ctx := context.Background()
+	request, err := pending.PendingRepo.TakePending(ctx, command.Callback.ID)
+	switch {
+	case errors.Is(err, pending.ErrNotFound):
+		editCallbackMessage(command, "This reset request is over, run /reset again", uuid.Nil)
+		return nil
+	case err != nil:
+		return err
+	case request.Kind != pending.KindReset || request.ChatID != command.ChatID:
+		return nil
+	default:
+	}
+	ids, err := request.AssignmentIDs()
+	if err != nil {
+		return err
+	}
+
+	deleted, change, err := assignment.AssignmentRepo.DeleteAssignmentsByID(
+		ctx,
+		command.ChatID,
+		ids,
+		utils.GetToday(),
+		getActor(command),
+	)
+	if err != nil {
+		return err
+	}
+	if request.SubjectID != 0 {
+		// Leaving operator shouldn't be assigned again by recurrences
+		endUserRecurrences(command.ChatID, request.SubjectID, command.Callback.Date)
+	}
+	refreshChatKeyboards(command.ChatID, 0)
+
+	text := fmt.Sprintf("Reset %d duties", len(deleted))
+	if len(deleted) != len(ids) {
+		text += fmt.Sprintf(", %d were already changed", len(ids)-len(deleted))
+	}
+	editCallbackMessage(command, text, change)
+	notifyResetOperators(command.ChatID, deleted)
+	for _, as := range deleted {
+		notifyWatchers(as)
+	}
+	return nil
+}
+
+// Stop recurring duties of user from the day on.
+// Rules are deleted if that day has come, otherwise they end the day before.
+func endUserRecurrences(chatID, userID int64, from time.Time) {
+	ctx := context.Background()
+	rules, err := recurrence.RecurrenceRepo.GetRecurrences(ctx, chatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return
+	}
+	until := from.AddDate(0, 0, -1)
+	for _, rule := range rules {
+		if rule.UserID != userID {
+			continue
+		}
+		if !from.After(utils.GetToday()) {
+			err = recurrence.RecurrenceRepo.DeleteRecurrence(ctx, rule.ID)
+		} else if rule.Until == nil || rule.Until.After(until) {
+			rule.Until = &until
+			err = recurrence.RecurrenceRepo.UpdateRecurrence(ctx, rule)
+		}
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+		}
+	}
+}
+
+// Replace message of pressed button and its buttons with text
+// and Undo button if change is given
+func editCallbackMessage(command Command, text string, change uuid.UUID) {
+	edit := tgbot.NewEditMessageText(command.ChatID, command.KeyboardID, text)
+	if change != uuid.Nil {
+		keyboard := undoKeyboard(change)
+		edit.ReplyMarkup = &keyboard
+	}
+	_, err := bot.Send(edit)
+	if err != nil {
+		logger.Log.Warn().Stack().Err(err).Send()
+	}
+}
+
+// Chat asks to confirm resets of other people's duties
+func isResetConfirmed(chatID int64) bool {
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), chatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return false
+	}
+	return settings.ConfirmReset
+}
+
+func askResetConfirmation(command Command, as assignment.Assignment) error {
+	request, err := pending.NewPending(
+		command.ChatID,
+		command.From.ID,
+		pending.KindReset,
+		[]uuid.UUID{as.ID},
+		viper.GetDuration("ResetConfirmTimeout"),
+	)
+	if err != nil {
+		return err
+	}
+	err = pending.PendingRepo.AddPending(context.Background(), request)
+	if err != nil {
+		return err
+	}
+
+	msg := tgbot.NewMessage(
+		command.ChatID,
+		fmt.Sprintf("Reset %s from %s%s?", as.Operator, formatDutyTime(as), roleSuffix(as)),
+	)
+	msg.ReplyMarkup = tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(
+		callbackButton("Reset", callback.Data{Action: callback.ActionConfirm, View: callback.ViewReset, ID: request.ID}),
+		callbackButton("Cancel", callback.Data{Action: callback.ActionCancel, View: callback.ViewReset, ID: request.ID}),
+	))
+	_, err = send(msg, command.ThreadID)
+	return err
+}
+
+// Reset button pressed by the one who asked for reset
+func confirmReset(command Command) error {
+	ctx := context.Background()
+	request, err := pending.PendingRepo.GetPending(ctx, command.Callback.ID)
+	switch {
+	case errors.Is(err, pending.ErrNotFound):
+		editCallbackMessage(command, "This reset request is over", uuid.Nil)
+		return nil
+	case err != nil:
+		return err
+	case request.Kind != pending.KindReset || request.ChatID != command.ChatID:
+		return nil
+	case request.UserID != command.From.ID:
+		return nil
+	default:
+	}
+	ids, err := request.AssignmentIDs()
+	if err != nil {
+		return err
+	}
+	if len(ids) != 1 {
+		return fmt.Errorf("reset request %s has %d duties", request.ID, len(ids))
+	}
+
+	// Button may be pressed twice, only the first press resets
+	_, err = pending.PendingRepo.TakePending(ctx, request.ID)
+	switch {
+	case errors.Is(err, pending.ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	default:
+	}
+
+	as, err := assignment.AssignmentRepo.GetAssignment(ctx, ids[0])
+	switch {
+	case errors.Is(err, assignment.ErrNotFound):
+		editCallbackMessage(command, "Duty is already reset", uuid.Nil)
+		return nil
+	case err != nil:
+		return err
+	default:
+	}
+
+	change, err := assignment.AssignmentRepo.DeleteAssignment(ctx, as.ID, getActor(command))
+	if err != nil {
+		return err
+	}
+	refreshChatKeyboards(command.ChatID, 0)
+
+	editCallbackMessage(
+		command,
+		fmt.Sprintf("%s is unassigned from %s%s", as.Operator, formatDutyTime(as), roleSuffix(as)),
+		change,
+	)
+	notifyResetOperators(command.ChatID, []assignment.Assignment{as})
+	notifyWatchers(as)
+	return nil
+}
+
+// Tell every operator which of their duties were reset
+func notifyResetOperators(chatID int64, deleted []assignment.Assignment) {
+	ctx := context.Background()
+	byOperator := make(map[int64][]assignment.Assignment)
+	operators := make([]int64, 0)
+	for _, as := range deleted {
+		if _, ok := byOperator[as.UserID]; !ok {
+			operators = append(operators, as.UserID)
+		}
+		byOperator[as.UserID] = append(byOperator[as.UserID], as)
+	}
+
+	lines := make([]string, 0, len(operators))
+	for _, userID := range operators {
+		duties := byOperator[userID]
+		dates := make([]string, 0, len(duties))
+		for _, as := range duties {
+			dates = append(dates, formatDutyTime(as)+roleSuffix(as))
+		}
+		lines = append(lines, fmt.Sprintf(
+			"%s is unassigned from %s",
+			mentionOperator(ctx, duties[0]),
+			html.EscapeString(strings.Join(dates, ", ")),
+		))
+	}
+	if len(lines) != 0 {
+		announce(chatID, strings.Join(lines, "\n"), HTMLParseMode)
+	}
+}
@@ -2,16 +2,22 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
 	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
 	"github.com/FedoseevAlex/DutyBot/internal/logger"
 	"github.com/FedoseevAlex/DutyBot/internal/utils"
 	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-var chatKeyboards = map[int64]int{}
+func callbackButton(text string, data callback.Data) tgbot.InlineKeyboardButton {
+	return tgbot.NewInlineKeyboardButtonData(text, callback.Encode(data))
+}
 
 func makeCalendarButtons(schedule []assignment.Assignment) tgbot.InlineKeyboardMarkup {
 	if len(schedule) == 0 {
@@ -21,37 +27,42 @@ func makeCalendarButtons(schedule []assignment.Assignment) tgbot.InlineKeyboardM
 
 	for _, assignment := range schedule {
 		var buttons []tgbot.InlineKeyboardButton
-		buttons = append(buttons, tgbot.NewInlineKeyboardButtonData(
+		buttons = append(buttons, callbackButton(
 			fmt.Sprintf("%s %s", assignment.At.Format("02 Jan, Mon"), assignment.Operator),
-			fmt.Sprintf("assign %s", assignment.At.Format(utils.AssignDateFormat))),
-		)
+			callback.Data{Action: callback.ActionAssign, View: callback.ViewWeek, Date: assignment.At},
+		))
 		if assignment.Operator != "" {
-			buttons = append(buttons, tgbot.NewInlineKeyboardButtonData(
+			buttons = append(buttons, callbackButton(
 				"reset",
-				fmt.Sprintf("reset %s", assignment.At.Format(utils.AssignDateFormat))),
-			)
+				callback.Data{Action: callback.ActionReset, View: callback.ViewWeek, Date: assignment.At},
+			))
 		}
 		keyboard = append(keyboard, tgbot.NewInlineKeyboardRow(buttons...))
 	}
 
 	scheduleStart := schedule[0].At
 	manageRow := tgbot.NewInlineKeyboardRow(
-		tgbot.NewInlineKeyboardButtonData(
-			"<",
-			fmt.Sprintf("showWeek %s", scheduleStart.Add(-utils.WeekDuration).Format(utils.AssignDateFormat)),
-		),
-		tgbot.NewInlineKeyboardButtonData(
-			">",
-			fmt.Sprintf("showWeek %s", scheduleStart.Add(utils.WeekDuration).Format(utils.AssignDateFormat)),
-		),
+		callbackButton("<", callback.Data{
+			Action: callback.ActionShow,
+			View:   callback.ViewWeek,
+			Date:   scheduleStart.Add(-utils.WeekDuration),
+		}),
+		callbackButton(">", callback.Data{
+			Action: callback.ActionShow,
+			View:   callback.ViewWeek,
+			Date:   scheduleStart.Add(utils.WeekDuration),
+		}),
 	)
 	keyboard = append(keyboard, manageRow)
 
 	return tgbot.NewInlineKeyboardMarkup(keyboard...)
 }
 
-func changeWeekOnKeyboard(chatID int64, keyboardID int, from time.Time) {
-	from = utils.GetStartOfWeek(from)
+func makeWeekButtons(chatID int64, from time.Time) tgbot.InlineKeyboardMarkup {
+	if isWeeklyChat(chatID) {
+		return makeDutyWeeksButtons(chatID, from)
+	}
+
 	schedule, err := assignment.AssignmentRepo.GetSchedule(
 		context.Background(),
 		from,
@@ -62,60 +73,137 @@ func changeWeekOnKeyboard(chatID int64, keyboardID int, from time.Time) {
 	if err != nil {
 		logger.Log.Error().Stack().Err(err).Send()
 	}
-	keyboard := makeCalendarButtons(schedule)
-	edit := tgbot.NewEditMessageReplyMarkup(chatID, keyboardID, keyboard)
+	return makeCalendarButtons(schedule)
+}
 
-	_, err = bot.Send(edit)
+func changeWeekOnKeyboard(chatID int64, keyboardID int, from time.Time) {
+	from = utils.GetStartOfWeek(from)
+	refreshKeyboard(chatID, keyboardID, from)
+
+	err := chat.ChatRepo.SetKeyboardShownFrom(context.Background(), chatID, keyboardID, from)
 	if err != nil {
-		logger.Log.Warn().Stack().Err(err).Send()
+		logger.Log.Error().Stack().Err(err).Send()
 	}
 }
 
 func refreshKeyboard(chatID int64, keyboardID int, from time.Time) {
 	from = utils.GetStartOfWeek(from)
-	schedule, err := assignment.AssignmentRepo.GetSchedule(
-		context.Background(),
-		from,
-		from.Add(utils.WeekDuration),
-		chatID,
-		true,
-	)
-	if err != nil {
-		logger.Log.Error().Stack().Err(err).Send()
-	}
-	keyboard := makeCalendarButtons(schedule)
-	edit := tgbot.NewEditMessageReplyMarkup(chatID, keyboardID, keyboard)
+	editKeyboard(chatID, keyboardID, makeWeekButtons(chatID, from))
+}
 
-	_, err = bot.Send(edit)
-	if err != nil {
+// Replace buttons of keyboard message.
+// Returns false if message is gone and keyboard should be forgotten.
+func editKeyboard(chatID int64, keyboardID int, keyboard tgbot.InlineKeyboardMarkup) bool {
+	edit := tgbot.NewEditMessageReplyMarkup(chatID, keyboardID, keyboard)
+	_, err := bot.Send(edit)
+
+	var tgErr *tgbot.Error
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &tgErr) && strings.Contains(tgErr.Message, "message is not modified"):
+		return true
+	case errors.As(err, &tgErr) && strings.Contains(tgErr.Message, "message to edit not found"):
+		return false
+	default:
 		logger.Log.Warn().Stack().Err(err).Send()
+		return true
 	}
 }
 
-func sendKeyboard(chatID int64, schedule []assignment.Assignment) {
-	keyboard := makeCalendarButtons(schedule)
-
+// Send new keyboard and remove previous ones from chat
+func sendKeyboard(
+	chatID int64,
+	threadID int,
+	view string,
+	shownFrom time.Time,
+	keyboard tgbot.InlineKeyboardMarkup,
+) {
 	answer := tgbot.NewMessage(chatID, "It's time to choose")
 	answer.ReplyMarkup = keyboard
 
-	response, err := bot.Send(answer)
+	response, err := send(answer, threadID)
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
+		return
 	}
 	logger.Log.Debug().Str("response", fmt.Sprintf("%+v", response)).Send()
 
-	removeOldKeyboard(chatID)
-	chatKeyboards[chatID] = response.MessageID
+	removeOldKeyboards(chatID)
+	err = chat.ChatRepo.AddKeyboard(context.Background(), chat.Keyboard{
+		ChatID:    chatID,
+		MessageID: response.MessageID,
+		View:      view,
+		ShownFrom: shownFrom,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+	}
 }
 
-func removeOldKeyboard(chatID int64) {
-	keyboardID, ok := chatKeyboards[chatID]
-	if !ok {
+func removeOldKeyboards(chatID int64) {
+	keyboards, err := chat.ChatRepo.GetKeyboards(context.Background(), chatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
 		return
 	}
-	rm := tgbot.NewDeleteMessage(chatID, keyboardID)
-	_, err := bot.Request(rm)
+
+	for _, keyboard := range keyboards {
+		rm := tgbot.NewDeleteMessage(chatID, keyboard.MessageID)
+		_, err := bot.Request(rm)
+		if err != nil {
+			logger.Log.Warn().Stack().Err(err).Send()
+		}
+		forgetKeyboard(keyboard)
+	}
+}
+
+// First day shown on keyboard or fallback if keyboard is unknown
+func getKeyboardShownFrom(chatID int64, keyboardID int, fallback time.Time) time.Time {
+	keyboards, err := chat.ChatRepo.GetKeyboards(context.Background(), chatID)
 	if err != nil {
-		logger.Log.Warn().Stack().Err(err).Send()
+		logger.Log.Error().Stack().Err(err).Send()
+		return fallback
+	}
+	for _, keyboard := range keyboards {
+		if keyboard.MessageID == keyboardID {
+			return keyboard.ShownFrom
+		}
+	}
+	return fallback
+}
+
+func forgetKeyboard(keyboard chat.Keyboard) {
+	err := chat.ChatRepo.DeleteKeyboard(context.Background(), keyboard.ChatID, keyboard.MessageID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+	}
+}
+
+// Redraw every open keyboard of chat after schedule change.
+// Keyboard that triggered change redraws itself, so it is skipped.
+func refreshChatKeyboards(chatID int64, exceptKeyboardID int) {
+	keyboards, err := chat.ChatRepo.GetKeyboards(context.Background(), chatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return
+	}
+
+	for _, keyboard := range keyboards {
+		if keyboard.MessageID == exceptKeyboardID {
+			continue
+		}
+
+		var buttons tgbot.InlineKeyboardMarkup
+		switch keyboard.View {
+		case chat.KeyboardMonth:
+			buttons = makeMonthButtons(chatID, monthView{Month: keyboard.ShownFrom})
+		default:
+			buttons = makeWeekButtons(chatID, keyboard.ShownFrom)
+		}
+		if !editKeyboard(chatID, keyboard.MessageID, buttons) {
+			forgetKeyboard(keyboard)
+		}
 	}
 }
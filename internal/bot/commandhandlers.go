@@ -4,8 +4,8 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -13,13 +13,18 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/absence"
 	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
+	"github.com/FedoseevAlex/DutyBot/internal/database/user"
 	"github.com/FedoseevAlex/DutyBot/internal/logger"
 	"github.com/FedoseevAlex/DutyBot/internal/utils"
 )
 
 const (
 	MarkdownParseMode    = "MarkdownV2"
+	HTMLParseMode        = "HTML"
 	FreeslotsThreshold   = 10
 	DefaultFreeSlotWeeks = 2
 	DefaultShowWeeks     = 2
@@ -29,10 +34,14 @@ const (
 
 type Command struct {
 	Action     string
-	Operator   string
+	From       user.User
 	ChatID     int64
 	Arguments  string
 	KeyboardID int
+	// Forum topic of the command. 0 for General topic and non forum chats
+	ThreadID int
+	// Decoded data of pressed inline button
+	Callback callback.Data
 }
 
 type CommandResult struct {
@@ -40,33 +49,33 @@ type CommandResult struct {
 	Message string
 }
 
-var handlers map[string]func(Command) error
-
-func initHandlers() {
-	handlers = map[string](func(Command) error){
-		"help":      help,
-		"assign":    assignAndPrint,
-		"show":      show,
-		"operator":  operator,
-		"freeslots": freeSlots,
-		"reset":     resetAssign,
-		"buttons":   showButtons,
-		"video":     reactToVideo,
-	}
-}
-
 func showButtons(command Command) error {
 	var err error
 
+	if view, arguments, _ := strings.Cut(command.Arguments, " "); view == "month" {
+		return showMonthButtons(command, strings.TrimSpace(arguments))
+	}
+
 	from := utils.GetToday()
 	if command.Arguments != "" {
-		from, err = parseTime(command.Arguments)
+		from, err = utils.ParseDate(command.Arguments)
 		if err != nil {
 			return err
 		}
 	}
 	from = utils.GetStartOfWeek(from)
 
+	if isWeeklyChat(command.ChatID) {
+		sendKeyboard(
+			command.ChatID,
+			command.ThreadID,
+			chat.KeyboardWeek,
+			from,
+			makeDutyWeeksButtons(command.ChatID, from),
+		)
+		return nil
+	}
+
 	schedule, err := assignment.AssignmentRepo.GetSchedule(
 		context.Background(),
 		from,
@@ -75,81 +84,132 @@ func showButtons(command Command) error {
 		true,
 	)
 	if err != nil {
-		sendMessage(
-			command.ChatID,
+		reply(
+			command,
 			fmt.Sprintf("Error getting assignments: %s", err),
 			NoParseMode,
 		)
 		return err
 	}
 
-	sendKeyboard(command.ChatID, schedule)
+	sendKeyboard(
+		command.ChatID,
+		command.ThreadID,
+		chat.KeyboardWeek,
+		from,
+		makeCalendarButtons(schedule),
+	)
 	return nil
 }
 
+// Dates of callback in the form date commands expect.
+// Lets callbacks reuse command handlers.
+func getCallbackArguments(chatID int64, data callback.Data) string {
+	args := make([]string, 0, 3)
+	for _, date := range []time.Time{data.Date, data.SecondDate} {
+		if !date.IsZero() {
+			args = append(args, date.Format(utils.AssignDateFormat))
+		}
+	}
+	if data.Shift != 0 {
+		args = append(args, getShiftStart(chatID, data.Shift))
+	}
+	return strings.Join(args, " ")
+}
+
 func processCallback(command Command) error {
-	switch command.Action {
-	case "assign":
-		err := assign(command)
-		if err != nil {
-			return err
+	data := command.Callback
+	if data.Action == callback.ActionIgnore {
+		return nil
+	}
+
+	switch data.View {
+	case callback.ViewWeek:
+		return processWeekCallback(command)
+	case callback.ViewMonth:
+		return processMonthCallback(command)
+	case callback.ViewDashboard:
+		switch data.Action {
+		case callback.ActionShow:
+			return refreshDashboard(command)
+		case callback.ActionGiveAway:
+			return giveAwayDuty(command)
+		case callback.ActionSwapFrom:
+			return chooseSwapDuty(command)
+		case callback.ActionSwap:
+			return swapDuty(command)
+		case callback.ActionConfirm:
+			return acceptSwap(command)
+		case callback.ActionCancel:
+			return declineSwap(command)
+		}
+	case callback.ViewIncident:
+		switch data.Action {
+		case callback.ActionAck:
+			return acknowledgeIncident(command)
+		case callback.ActionResolve:
+			return resolveIncidentCallback(command)
 		}
-		assignDate, _ := parseTime(command.Arguments)
-		refreshKeyboard(command.ChatID, command.KeyboardID, assignDate)
+	case callback.ViewReset:
+		return processResetCallback(command)
+	case callback.ViewUndo:
+		return undoChange(command)
+	case callback.ViewWatch:
+		return takeWatchedSlot(command)
+	case callback.ViewShift:
+		return processShiftCallback(command)
+	}
+	return fmt.Errorf("unknown callback action %d for view %d", data.Action, data.View)
+}
+
+func processWeekCallback(command Command) error {
+	date := command.Callback.Date
+	if date.IsZero() {
+		return fmt.Errorf("no date in week callback %d", command.Callback.Action)
+	}
 
-	case "showWeek":
-		from, err := parseTime(command.Arguments)
+	switch command.Callback.Action {
+	case callback.ActionShow:
+		changeWeekOnKeyboard(command.ChatID, command.KeyboardID, date)
+		return nil
+	case callback.ActionAssign:
+		err := assign(command)
 		if err != nil {
 			return err
 		}
-		changeWeekOnKeyboard(command.ChatID, command.KeyboardID, from)
-
-	case "reset":
+	case callback.ActionReset:
 		err := resetAssign(command)
 		if err != nil {
 			return err
 		}
-		date, _ := parseTime(command.Arguments)
-		refreshKeyboard(command.ChatID, command.KeyboardID, date)
+	default:
+		return fmt.Errorf("unknown week callback %d", command.Callback.Action)
 	}
+	refreshKeyboard(
+		command.ChatID,
+		command.KeyboardID,
+		getKeyboardShownFrom(command.ChatID, command.KeyboardID, date),
+	)
 	return nil
 }
 
 func processCommands(command Command) error {
-	handler, ok := handlers[command.Action]
+	spec, ok := handlers[command.Action]
 	if !ok {
 		answer := tgbot.NewMessage(command.ChatID, "Unknown command. Try /help")
-		_, err := bot.Send(answer)
+		_, err := send(answer, command.ThreadID)
 		if err != nil {
 			logger.Log.Error().Err(err).Send()
 		}
 		return errors.New("Unknown command")
 	}
-	err := handler(command)
-	return err
-}
 
-func help(command Command) error {
-	helpString := `Usage:
-/help - look at this message again
-/operator - tag current duty
-/show [weeks (default=2)] - show duty schedule for some weeks ahead
-/assign date - assign yourself for duty. Date should be in format DD-MM-YYYY
-/reset [date default=Today] - clear specified date from assignments
-/freeslots [weeks default=1] - show free duty slots
-/buttons - show buttons for assignment
-
-Found a bug? Want some features?
-Feel free to make an issue:
-https://github.com/FedoseevAlex/DutyBot/issues
-`
-	answer := tgbot.NewMessage(command.ChatID, helpString)
-	_, err := bot.Send(answer)
-	if err != nil {
-		logger.Log.Error().Err(err).Send()
+	refusal, err := spec.checkScope(command)
+	if refusal != "" {
+		reply(command, refusal, NoParseMode)
 		return err
 	}
-	return nil
+	return spec.Handler(command)
 }
 
 func reactToVideo(command Command) error {
@@ -157,8 +217,8 @@ func reactToVideo(command Command) error {
 		return nil
 	}
 
-	reply := tgbot.NewMessage(command.ChatID, "Hehehehehe")
-	_, err := bot.Send(reply)
+	answer := tgbot.NewMessage(command.ChatID, "Hehehehehe")
+	_, err := send(answer, command.ThreadID)
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
 		return err
@@ -167,31 +227,41 @@ func reactToVideo(command Command) error {
 }
 
 func operator(command Command) error {
-	as, err := assignment.AssignmentRepo.GetAssignmentByDate(
+	now := utils.GetNow()
+	as, err := assignment.AssignmentRepo.GetAssignmentAt(
 		context.Background(),
-		utils.GetToday(),
-		command.ChatID)
+		now,
+		command.ChatID,
+		assignment.RolePrimary)
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
-		reply := tgbot.NewMessage(command.ChatID, "Couldn't fetch today's duty.")
-		_, err := bot.Send(reply)
+		answer := tgbot.NewMessage(command.ChatID, "Couldn't fetch today's duty.")
+		_, err := send(answer, command.ThreadID)
 		if err != nil {
 			logger.Log.Error().Err(err).Send()
 		}
 		return err
 	}
-	if as.Operator == "" {
-		reply := tgbot.NewMessage(command.ChatID, "No one is assigned for today")
-		_, err := bot.Send(reply)
+	backup := mentionBackup(context.Background(), command.ChatID, now)
+	if as.Operator == "" && backup == "" {
+		answer := tgbot.NewMessage(command.ChatID, "No one is assigned for today")
+		_, err := send(answer, command.ThreadID)
 		if err != nil {
 			logger.Log.Error().Err(err).Send()
 		}
 		return nil
 	}
 
-	operator := fmt.Sprintf("@%s", as.Operator)
-	reply := tgbot.NewMessage(command.ChatID, operator)
-	_, err = bot.Send(reply)
+	text := "No primary operator"
+	if as.Operator != "" {
+		text = mentionOperator(context.Background(), as)
+	}
+	if backup != "" {
+		text = fmt.Sprintf("%s\nBackup: %s", text, backup)
+	}
+	answer := tgbot.NewMessage(command.ChatID, text)
+	answer.ParseMode = HTMLParseMode
+	_, err = send(answer, command.ThreadID)
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
 		return err
@@ -199,22 +269,17 @@ func operator(command Command) error {
 	return nil
 }
 
-// This function assumes that date
-// is ordered like DD MM YYYY
-func parseTime(probablyTime string) (time.Time, error) {
-	r := regexp.MustCompile("([0-9]{1,2}).*?([0-9]{1,2}).*?([0-9]{4})")
-	if !r.MatchString(probablyTime) {
-		return time.Time{}, fmt.Errorf("'%s' is not look like DD MM YYYY", probablyTime)
-	}
-	parts := r.FindAllStringSubmatch(probablyTime, 1)[0]
-	date := fmt.Sprintf("%02s", parts[1])
-	month := fmt.Sprintf("%02s", parts[2])
-	year := parts[3]
-	t, err := time.Parse(utils.DateFormat, fmt.Sprintf("%s-%s-%s", year, month, date))
-	return t, err
+// Reply to command in forum topic it came from
+func reply(command Command, message string, parseMode string) {
+	sendMessage(command.ChatID, command.ThreadID, message, parseMode)
 }
 
-func sendMessage(chatID int64, message string, parseMode string) {
+// Post message to forum topic chosen for announcements
+func announce(chatID int64, message string, parseMode string) {
+	sendMessage(chatID, getAnnounceThreadID(chatID), message, parseMode)
+}
+
+func sendMessage(chatID int64, threadID int, message string, parseMode string) {
 	msg := tgbot.NewMessage(
 		chatID,
 		message,
@@ -224,14 +289,23 @@ func sendMessage(chatID int64, message string, parseMode string) {
 		msg.ParseMode = parseMode
 	}
 
-	_, err := bot.Send(msg)
+	_, err := send(msg, threadID)
+	if newChatID, ok := migratedTo(err); ok {
+		// Chat was upgraded while nobody was listening.
+		// Plain groups have no topics, so thread is dropped.
+		if err := migrateChat(chatID, newChatID); err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+		}
+		msg.ChatID = newChatID
+		_, err = send(msg, 0)
+	}
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
 	}
 }
 
 func checkDate(possibleDate string) (time.Time, error) {
-	dutydate, err := parseTime(possibleDate)
+	dutydate, err := utils.ParseDate(possibleDate)
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
 		return time.Time{}, err
@@ -257,103 +331,193 @@ func assignAndPrint(command Command) error {
 	if err != nil {
 		return err
 	}
-	assignmentDate, _ := parseTime(command.Arguments)
-	_, assignmentWeek := assignmentDate.ISOWeek()
-	_, currentWeek := utils.GetToday().ISOWeek()
-	weeks := assignmentWeek - currentWeek
-	if weeks < DefaultShowWeeks {
-		weeks = DefaultShowWeeks
-	}
-
-	assignments, err := getAssignmentsTable(command.ChatID, weeks)
+	assignments, err := getAssignmentsTable(command.ChatID, getAssignedWeeks(command))
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
-		sendMessage(command.ChatID, err.Error(), NoParseMode)
+		reply(command, err.Error(), NoParseMode)
 		return err
 	}
-	sendMessage(
-		command.ChatID,
+	reply(
+		command,
 		fmt.Sprintf("```\n%s\n```", assignments),
 		MarkdownParseMode,
 	)
 	return nil
 }
 
+// Weeks to show for the schedule to include the assigned duty.
+// Arguments are parsed the same way /assign does.
+func getAssignedWeeks(command Command) int {
+	arguments, _ := cutRole(command.Arguments)
+	if args := strings.Fields(arguments); len(args) != 0 && args[0] == everyArgument {
+		return DefaultShowWeeks
+	}
+	dutydate, err := parseDutyDate(command.ChatID, arguments)
+	if err != nil {
+		return DefaultShowWeeks
+	}
+	// Counted from current week, so it works across new year too
+	weeks := int(dutydate.Sub(utils.GetStartOfWeek(utils.GetToday()))/utils.WeekDuration) + 1
+	if weeks < DefaultShowWeeks {
+		weeks = DefaultShowWeeks
+	}
+	return weeks
+}
+
 func assign(command Command) error {
-	dutydate, err := checkDate(command.Arguments)
+	arguments, role := cutRole(command.Arguments)
+	if args := strings.Fields(arguments); len(args) != 0 && args[0] == everyArgument {
+		return assignEvery(command, args[1:], role)
+	}
+	dutydate, err := parseDutyDate(command.ChatID, arguments)
 	if err != nil {
 		logger.Log.Error().Stack().Err(err).Send()
-		sendMessage(command.ChatID, err.Error(), NoParseMode)
+		reply(command, err.Error(), NoParseMode)
 		return err
 	}
 
-	as, err := assignment.AssignmentRepo.GetAssignmentByDate(
-		context.Background(),
-		dutydate,
-		command.ChatID)
+	startsAt, endsAt, err := getDutyBounds(command.ChatID, dutydate, arguments)
+	var noShift noShiftError
+	if errors.As(err, &noShift) && role == assignment.RolePrimary {
+		return askShift(command, callback.ActionAssign, dutydate, noShift.templates)
+	}
 	if err != nil {
-		logger.Log.
-			Error().
-			Str("operation", "assign").
-			Stack().
-			Err(err).
-			Send()
+		reply(command, err.Error(), NoParseMode)
 		return err
 	}
-	if as.Operator != "" {
-		sendMessage(
+
+	allowed, err := checkSlotAllowed(command, role, startsAt, endsAt)
+	if err != nil || !allowed {
+		return err
+	}
+
+	return takeSlotAndPrint(command, assignment.Assignment{
+		ChatID:    command.ChatID,
+		At:        dutydate,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		UserID:    command.From.ID,
+		Operator:  command.From.DisplayName(),
+		Role:      role,
+		ID:        uuid.New(),
+		CreatedAt: utils.GetToday(),
+	})
+}
+
+// Check the user may take the slot and tell why not otherwise
+func checkSlotAllowed(command Command, role string, startsAt, endsAt time.Time) (bool, error) {
+	var taken [2]assignment.Assignment
+	for i, r := range []string{role, otherRole(role)} {
+		var err error
+		taken[i], err = assignment.AssignmentRepo.GetAssignmentAt(
+			context.Background(),
+			startsAt,
 			command.ChatID,
+			r)
+		if err != nil {
+			logger.Log.
+				Error().
+				Str("operation", "assign").
+				Stack().
+				Err(err).
+				Send()
+			return false, err
+		}
+	}
+	if as := taken[0]; as.Operator != "" {
+		reply(
+			command,
 			fmt.Sprintf(
 				"`%s` is taken by `%s` try `/reset %s`",
-				as.At.Format(utils.AssignDateFormat),
+				formatDutyTime(as),
 				as.Operator,
-				as.At.Format(utils.AssignDateFormat),
+				getSlotArguments(as),
 			),
 			MarkdownParseMode,
 		)
-		return nil
+		return false, nil
+	}
+	if taken[1].UserID == command.From.ID {
+		reply(command, "Primary and backup operator should be different people", NoParseMode)
+		return false, nil
 	}
 
-	a := assignment.Assignment{
-		ChatID:    command.ChatID,
-		At:        dutydate,
-		Operator:  command.Operator,
-		ID:        uuid.New(),
-		CreatedAt: utils.GetToday(),
+	absent, ok, err := findAbsence(context.Background(), command.From.ID, startsAt, endsAt)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return false, err
+	}
+	if ok {
+		reply(
+			command,
+			fmt.Sprintf("You are away %s, try /away cancel first", formatAbsence(absent)),
+			NoParseMode,
+		)
+		return false, nil
 	}
+	return true, nil
+}
+
+func takeSlotAndPrint(command Command, a assignment.Assignment) error {
 	logger.Log.Printf("new assignment: %+v", a)
-	err = assignment.AssignmentRepo.AddAssignment(
+	change, err := assignment.AssignmentRepo.TakeSlot(
 		context.Background(),
 		a,
+		getActor(command),
 	)
+	if errors.Is(err, assignment.ErrSlotTaken) {
+		reply(command, "Somebody has just taken this slot", NoParseMode)
+		return nil
+	}
 	if err != nil {
 		logger.Log.Error().Stack().Err(err).Send()
 		return err
 	}
 
+	refreshChatKeyboards(command.ChatID, command.KeyboardID)
+	replyWithUndo(
+		command,
+		fmt.Sprintf(
+			"%s is on duty %s%s",
+			mentionOperator(context.Background(), a),
+			formatDutyTime(a),
+			roleSuffix(a),
+		),
+		HTMLParseMode,
+		change,
+	)
 	return nil
 }
 
 func resetAssign(command Command) error {
+	if isBulkReset(command.Arguments) {
+		return bulkReset(command)
+	}
+
 	var err error
 	dutydate := utils.GetToday()
+	arguments, role := cutRole(command.Arguments)
 
-	if command.Arguments != "" {
-		dutydate, err = checkDate(command.Arguments)
+	if arguments != "" {
+		dutydate, err = parseDutyDate(command.ChatID, arguments)
 		if err != nil {
 			logger.Log.Error().Err(err).Send()
-			sendMessage(command.ChatID, err.Error(), NoParseMode)
+			reply(command, err.Error(), NoParseMode)
 			return err
 		}
 	}
 
-	as, err := assignment.AssignmentRepo.GetAssignmentByDate(
-		context.Background(),
-		dutydate,
-		command.ChatID)
-	if err != nil {
-		sendMessage(
-			command.ChatID,
+	as, err := getAssignmentToReset(command.ChatID, dutydate, arguments, role)
+	var noShift noShiftError
+	switch {
+	case errors.As(err, &noShift) && role == assignment.RolePrimary:
+		return askShift(command, callback.ActionReset, dutydate, noShift.templates)
+	case errors.As(err, &noShift):
+		reply(command, err.Error(), NoParseMode)
+		return nil
+	case err != nil:
+		reply(
+			command,
 			fmt.Sprintf(
 				"Error getting assignments for %s",
 				dutydate.Format(utils.AssignDateFormat),
@@ -365,26 +529,158 @@ func resetAssign(command Command) error {
 	if as.Operator == "" {
 		return nil
 	}
+	if as.UserID != command.From.ID && isResetConfirmed(command.ChatID) {
+		return askResetConfirmation(command, as)
+	}
+	return resetAndPrint(command, as)
+}
 
-	err = assignment.AssignmentRepo.DeleteAssignment(context.Background(), as.ID)
+func resetAndPrint(command Command, as assignment.Assignment) error {
+	change, err := assignment.AssignmentRepo.DeleteAssignment(context.Background(), as.ID, getActor(command))
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
-		sendMessage(
-			command.ChatID,
+		reply(
+			command,
 			"failed to reset assignments",
 			NoParseMode,
 		)
 		return err
 	}
+	refreshChatKeyboards(command.ChatID, command.KeyboardID)
+	notifyWatchers(as)
+
+	replyWithUndo(
+		command,
+		fmt.Sprintf(
+			"%s is unassigned from %s%s",
+			mentionOperator(context.Background(), as),
+			formatDutyTime(as),
+			roleSuffix(as),
+		),
+		HTMLParseMode,
+		change,
+	)
+	return nil
+}
+
+// Check that command author is allowed to manage chat.
+// In private chats user is always an admin.
+func isChatAdmin(command Command) (bool, error) {
+	if command.ChatID == command.From.ID {
+		return true, nil
+	}
+
+	member, err := bot.GetChatMember(tgbot.GetChatMemberConfig{
+		ChatConfigWithUser: tgbot.ChatConfigWithUser{
+			ChatID: command.ChatID,
+			UserID: command.From.ID,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return member.IsCreator() || member.IsAdministrator(), nil
+}
+
+// Get primary duty of the date to swap.
+// Empty assignment means the user was already told why it can't be swapped.
+func getSwapDuty(command Command, possibleDate string) (assignment.Assignment, error) {
+	dutydate, err := parseDutyDate(command.ChatID, possibleDate)
+	if err != nil {
+		logger.Log.Error().Err(err).Send()
+		reply(command, err.Error(), NoParseMode)
+		return assignment.Assignment{}, err
+	}
 
-	sendMessage(
+	as, err := assignment.AssignmentRepo.GetAssignmentByDate(
+		context.Background(),
+		dutydate,
 		command.ChatID,
+		assignment.RolePrimary)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return as, err
+	}
+	if as.Operator == "" {
+		reply(
+			command,
+			fmt.Sprintf(
+				"%s is free, try /assign %s",
+				dutydate.Format(utils.AssignDateFormat),
+				dutydate.Format(utils.AssignDateFormat),
+			),
+			NoParseMode,
+		)
+	}
+	return as, nil
+}
+
+func swapAssign(command Command) error {
+	const swapArgs = 2
+	dates := strings.Fields(command.Arguments)
+	if len(dates) != swapArgs {
+		reply(command, "Usage: /swap DD-MM-YYYY DD-MM-YYYY", NoParseMode)
+		return nil
+	}
+
+	var swapped [swapArgs]assignment.Assignment
+	for i, possibleDate := range dates {
+		as, err := getSwapDuty(command, possibleDate)
+		if err != nil || as.Operator == "" {
+			return err
+		}
+		swapped[i] = as
+	}
+	if swapped[0].UserID == swapped[1].UserID {
+		reply(command, "Nothing to swap", NoParseMode)
+		return nil
+	}
+	absent, err := findSwapAbsence(context.Background(), swapped[0], swapped[1])
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return err
+	}
+	if absent != "" {
+		reply(command, "Can't swap: "+absent, NoParseMode)
+		return nil
+	}
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		return askSwap(command, swapped)
+	}
+	return swapAndPrint(command, swapped)
+}
+
+func swapAndPrint(command Command, swapped [2]assignment.Assignment) error {
+	change, err := assignment.AssignmentRepo.SwapAssignments(
+		context.Background(),
+		swapped[0].ID,
+		swapped[1].ID,
+		getActor(command),
+	)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "failed to swap assignments", NoParseMode)
+		return err
+	}
+	refreshChatKeyboards(command.ChatID, command.KeyboardID)
+
+	replyWithUndo(
+		command,
 		fmt.Sprintf(
-			"@%s is unassigned from %s",
-			as.Operator,
-			dutydate.Format(utils.AssignDateFormat),
+			"%s is on duty %s, %s is on duty %s",
+			mentionOperator(context.Background(), swapped[1]),
+			swapped[0].At.Format(utils.AssignDateFormat),
+			mentionOperator(context.Background(), swapped[0]),
+			swapped[1].At.Format(utils.AssignDateFormat),
 		),
-		NoParseMode,
+		HTMLParseMode,
+		change,
 	)
 	return nil
 }
@@ -414,8 +710,8 @@ func checkWeeks(weekArgument string) (int, error) {
 func freeSlots(command Command) error {
 	weeks, err := checkWeeks(command.Arguments)
 	if err != nil {
-		sendMessage(
-			command.ChatID,
+		reply(
+			command,
 			err.Error(),
 			NoParseMode,
 		)
@@ -428,15 +724,18 @@ func freeSlots(command Command) error {
 		return err
 	}
 
-	sendMessage(command.ChatID, table, NoParseMode)
+	reply(command, table, NoParseMode)
 	return nil
 }
 
 func getFreeSlotsTable(chatID int64, weeks int) (string, error) {
+	weekly := isWeeklyChat(chatID)
 	slots, err := assignment.AssignmentRepo.GetFreeSlots(
 		context.Background(),
 		utils.GetToday().Add(utils.WeekDuration*time.Duration(weeks)),
-		chatID)
+		chatID,
+		weekly,
+	)
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
 		return "", err
@@ -448,7 +747,11 @@ func getFreeSlotsTable(chatID int64, weeks int) (string, error) {
 
 	freeSlots := utils.NewPrettyTable()
 	for _, slot := range slots {
-		freeSlots.AddRow([]string{"/assign", slot.Format(utils.AssignDateFormat)})
+		row := []string{"/assign", slot.Format(utils.AssignDateFormat)}
+		if weekly {
+			row = append(row, formatWeek(slot))
+		}
+		freeSlots.AddRow(row)
 	}
 	table, err := freeSlots.String()
 	if err != nil {
@@ -462,8 +765,8 @@ func show(command Command) error {
 	weeks, err := checkWeeks(command.Arguments)
 	if err != nil {
 		logger.Log.Error().Err(err).Send()
-		sendMessage(
-			command.ChatID,
+		reply(
+			command,
 			err.Error(),
 			NoParseMode,
 		)
@@ -473,8 +776,8 @@ func show(command Command) error {
 	table, err := getAssignmentsTable(command.ChatID, weeks)
 	if err != nil {
 		logger.Log.Error().Stack().Err(err).Send()
-		sendMessage(
-			command.ChatID,
+		reply(
+			command,
 			fmt.Sprintf("Tabulation error: %s", err.Error()),
 			NoParseMode,
 		)
@@ -484,25 +787,50 @@ func show(command Command) error {
 	if table == "" {
 		table = "Nothing to show"
 	}
-	sendMessage(command.ChatID, fmt.Sprintf("```\n%s\n```", table), MarkdownParseMode)
+	reply(command, fmt.Sprintf("```\n%s\n```", table), MarkdownParseMode)
 	return nil
 }
 
 func getAssignmentsTable(chatID int64, weeks int) (string, error) {
+	due := utils.GetToday().Add(utils.WeekDuration * time.Duration(weeks))
 	assignments, err := assignment.AssignmentRepo.GetAssignmentSchedule(
 		context.Background(),
-		utils.GetToday().Add(utils.WeekDuration*time.Duration(weeks)),
+		due,
 		chatID)
 	if err != nil {
 		logger.Log.Error().Stack().Err(err).Send()
 		return "", fmt.Errorf("couldn't get assignments")
 	}
+	// Operators assigned during their absence are flagged
+	absences, err := absence.AbsenceRepo.GetAbsences(context.Background(), utils.GetToday(), due)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+	}
 
-	schedule := utils.NewPrettyTable()
-
+	// Primary and backup operators of the same slot share a row
+	slots := make([]time.Time, 0, len(assignments))
+	rows := make(map[time.Time][]string, len(assignments))
 	for _, ass := range assignments {
-		dutyDate := ass.At.Format(utils.HumanDateFormat)
-		schedule.AddRow([]string{ass.Operator, dutyDate})
+		row, ok := rows[ass.StartsAt]
+		if !ok {
+			row = []string{"", "", formatDutyTime(ass)}
+			slots = append(slots, ass.StartsAt)
+		}
+		operator := ass.Operator
+		if isAway(absences, ass) {
+			operator += " (away)"
+		}
+		if ass.IsBackup() {
+			row[1] = operator
+		} else {
+			row[0] = operator
+		}
+		rows[ass.StartsAt] = row
+	}
+
+	schedule := utils.NewPrettyTable()
+	for _, slot := range slots {
+		schedule.AddRow(rows[slot])
 	}
 	table, err := schedule.String()
 	if err != nil {
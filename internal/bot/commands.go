@@ -0,0 +1,436 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+)
+
+// Where command makes sense. Commands run only where their scope allows.
+// Commands without scope work everywhere but are not advertised.
+type commandScope uint8
+
+const (
+	scopeGroup commandScope = 1 << iota
+	scopePrivate
+	// Group chat admins only
+	scopeAdmin
+)
+
+type commandSpec struct {
+	Name string
+	// Argument syntax for /help
+	Args        string
+	Description string
+	// Descriptions for telegram menu keyed by IETF language code.
+	// Description is used for other languages.
+	Translations map[string]string
+	Scope        commandScope
+	Handler      func(Command) error
+}
+
+const helpFooter = `
+Found a bug? Want some features?
+Feel free to make an issue:
+https://github.com/FedoseevAlex/DutyBot/issues
+`
+
+// Commands in order of appearance in /help and telegram menu
+var commands []commandSpec
+
+var handlers map[string]commandSpec
+
+// Commands everybody starts with
+var generalCommands = []commandSpec{
+	{
+		Name:         "help",
+		Description:  "look at this message again",
+		Translations: map[string]string{"ru": "показать справку"},
+		Scope:        scopeGroup | scopePrivate,
+		Handler:      help,
+	},
+	{
+		Name:         "operator",
+		Description:  "tag current duty and backup",
+		Translations: map[string]string{"ru": "позвать дежурного"},
+		Scope:        scopeGroup,
+		Handler:      operator,
+	},
+	{
+		Name:         "show",
+		Args:         "[weeks (default=2)]",
+		Description:  "show duty schedule for some weeks ahead",
+		Translations: map[string]string{"ru": "расписание дежурств на несколько недель"},
+		Scope:        scopeGroup,
+		Handler:      show,
+	},
+}
+
+// Taking and giving away duties
+var scheduleCommands = []commandSpec{
+	{
+		Name: "assign",
+		Args: "date [HH:MM] [backup] | every wed [until DD-MM-YYYY] [backup]",
+		Description: "assign yourself for duty. Date should be in format DD-MM-YYYY or YYYY-Www in weekly chats, " +
+			"time is shift start. With every the duty repeats weekly",
+		Translations: map[string]string{
+			"ru": "записаться на дежурство. Дата в формате DD-MM-YYYY или YYYY-Www в понедельных чатах, " +
+				"время - начало смены. С every дежурство повторяется каждую неделю",
+		},
+		Scope:   scopeGroup,
+		Handler: assignAndPrint,
+	},
+	{
+		Name:        "reset",
+		Args:        "[date default=Today] [backup] | @user [from DD-MM-YYYY] | range DD-MM-YYYY..DD-MM-YYYY",
+		Description: "clear specified date from assignments. Admins can reset every duty of user or range of days",
+		Translations: map[string]string{
+			"ru": "снять дежурного (или запасного с backup) с даты или недели. " +
+				"Админы могут снять все дежурства пользователя или диапазон дней",
+		},
+		Scope:   scopeGroup,
+		Handler: resetAssign,
+	},
+	{
+		Name:         "swap",
+		Args:         "date date",
+		Description:  "exchange operators of two days (yours or, for admins, any)",
+		Translations: map[string]string{"ru": "поменять дежурных двух дней местами (свои или, для админов, любые)"},
+		Scope:        scopeGroup,
+		Handler:      swapAssign,
+	},
+	{
+		Name:         "history",
+		Args:         "[date]",
+		Description:  "show latest schedule changes",
+		Translations: map[string]string{"ru": "последние изменения расписания"},
+		Scope:        scopeGroup,
+		Handler:      showHistory,
+	},
+	{
+		Name:         "freeslots",
+		Args:         "[weeks default=1]",
+		Description:  "show free duty slots",
+		Translations: map[string]string{"ru": "свободные дни для дежурства"},
+		Scope:        scopeGroup,
+		Handler:      freeSlots,
+	},
+	{
+		Name:         "buttons",
+		Args:         "[month] [date]",
+		Description:  "show buttons for assignment, week or month view",
+		Translations: map[string]string{"ru": "кнопки для записи на дежурство"},
+		Scope:        scopeGroup,
+		Handler:      showButtons,
+	},
+}
+
+// Overview of duties and incidents
+var overviewCommands = []commandSpec{
+	{
+		Name:         "my",
+		Description:  "your upcoming duties in all chats",
+		Translations: map[string]string{"ru": "ваши дежурства во всех чатах"},
+		Scope:        scopePrivate,
+		Handler:      showMyDuties,
+	},
+	{
+		Name:         "stats",
+		Args:         "[month|quarter|year|YYYY-MM|YYYY (default=month)]",
+		Description:  "show who took how many duties",
+		Translations: map[string]string{"ru": "кто сколько дежурил"},
+		Scope:        scopeGroup,
+		Handler:      showStats,
+	},
+	{
+		Name:         "incidents",
+		Description:  "show not resolved incidents",
+		Translations: map[string]string{"ru": "нерешённые инциденты"},
+		Scope:        scopeGroup,
+		Handler:      listIncidents,
+	},
+}
+
+// Where bot posts and what it receives from other services
+var integrationCommands = []commandSpec{
+	{
+		Name:         "topic",
+		Args:         "[reset]",
+		Description:  "post announcements to this forum topic",
+		Translations: map[string]string{"ru": "публиковать объявления в этой теме"},
+		Scope:        scopeAdmin,
+		Handler:      setAnnounceTopic,
+	},
+	{
+		Name:         "webhook",
+		Args:         "[add url | remove url | list]",
+		Description:  "manage event webhooks",
+		Translations: map[string]string{"ru": "настроить вебхуки"},
+		Scope:        scopeAdmin,
+		Handler:      manageWebhooks,
+	},
+	{
+		Name:         "alerts",
+		Args:         "[route name | unroute name]",
+		Description:  "post alertmanager alerts for receiver here",
+		Translations: map[string]string{"ru": "присылать алерты alertmanager в этот чат"},
+		Scope:        scopeAdmin,
+		Handler:      manageAlertRoutes,
+	},
+}
+
+// Planning schedule ahead
+var planningCommands = []commandSpec{
+	{
+		Name:         "away",
+		Args:         "[DD-MM-YYYY..DD-MM-YYYY [reason] | cancel DD-MM-YYYY]",
+		Description:  "tell when you can't be on duty",
+		Translations: map[string]string{"ru": "сообщить, когда не можешь дежурить"},
+		Scope:        scopeGroup | scopePrivate,
+		Handler:      away,
+	},
+	{
+		Name:         "prefer",
+		Args:         "[avoid mon,fri | max N | no-consecutive | no-after-holiday | reset]",
+		Description:  "tell planner when you'd rather not be on duty",
+		Translations: map[string]string{"ru": "пожелания к расписанию"},
+		Scope:        scopeGroup,
+		Handler:      prefer,
+	},
+	{
+		Name:         "plan",
+		Args:         "month [MM-YYYY] [apply]",
+		Description:  "preview or assign fair plan for free days of month",
+		Translations: map[string]string{"ru": "составить расписание на месяц"},
+		Scope:        scopeAdmin,
+		Handler:      planSchedule,
+	},
+	{
+		Name:         "watch",
+		Args:         "[DD-MM-YYYY | cancel DD-MM-YYYY]",
+		Description:  "get a private message when taken day is free",
+		Translations: map[string]string{"ru": "сообщить, когда занятый день освободится"},
+		Scope:        scopeGroup,
+		Handler:      watchSlot,
+	},
+	{
+		Name:         "recurring",
+		Args:         "[cancel N | edit N weekday [until DD-MM-YYYY | until never]]",
+		Description:  "list, change or cancel recurring duties",
+		Translations: map[string]string{"ru": "повторяющиеся дежурства"},
+		Scope:        scopeGroup,
+		Handler:      manageRecurrences,
+	},
+}
+
+// Compensation for duties
+var payrollCommands = []commandSpec{
+	{
+		Name:         "ledger",
+		Args:         "[DD-MM-YYYY] | redeem @user N [DD-MM-YYYY] [note] | unredeem @user DD-MM-YYYY",
+		Description:  "show days off earned for weekend and holiday duties",
+		Translations: map[string]string{"ru": "отгулы за дежурства в выходные и праздники"},
+		Scope:        scopeGroup,
+		Handler:      showLedger,
+	},
+	{
+		Name:         "report",
+		Args:         "[YYYY-MM (default=previous month)] | monthly on|off | to me|chat",
+		Description:  "send duty report for payroll as CSV",
+		Translations: map[string]string{"ru": "отчёт о дежурствах в CSV"},
+		Scope:        scopeGroup,
+		Handler:      showReport,
+	},
+}
+
+// Chat settings
+var settingsCommands = []commandSpec{
+	{
+		Name:         "confirmreset",
+		Args:         "[on|off]",
+		Description:  "ask for confirmation before resetting duty of somebody else",
+		Translations: map[string]string{"ru": "подтверждать снятие чужого дежурства"},
+		Scope:        scopeAdmin,
+		Handler:      setConfirmReset,
+	},
+	{
+		Name:         "period",
+		Args:         "[day|week]",
+		Description:  "hand over duty daily or weekly",
+		Translations: map[string]string{"ru": "передавать дежурство каждый день или неделю"},
+		Scope:        scopeAdmin,
+		Handler:      setDutyPeriod,
+	},
+	{
+		Name:         "shifts",
+		Args:         "[set HH:MM-HH:MM ... | reset]",
+		Description:  "split duty day into shifts",
+		Translations: map[string]string{"ru": "разбить день дежурства на смены"},
+		Scope:        scopeAdmin,
+		Handler:      manageShifts,
+	},
+}
+
+// Not advertised anywhere
+var hiddenCommands = []commandSpec{
+	{
+		Name:    "video",
+		Handler: reactToVideo,
+	},
+}
+
+func initHandlers() {
+	// Order of groups is the order of appearance in /help and telegram menu
+	groups := [][]commandSpec{
+		generalCommands,
+		scheduleCommands,
+		overviewCommands,
+		integrationCommands,
+		planningCommands,
+		payrollCommands,
+		settingsCommands,
+		hiddenCommands,
+	}
+	commands = nil
+	for _, group := range groups {
+		commands = append(commands, group...)
+	}
+
+	handlers = make(map[string]commandSpec, len(commands))
+	for _, spec := range commands {
+		handlers[spec.Name] = spec
+	}
+}
+
+// Check command may be run where it was sent.
+// Returns text explaining why not, empty if it may.
+func (spec commandSpec) checkScope(command Command) (string, error) {
+	private := isPrivateChat(command)
+	switch {
+	case spec.Scope == 0:
+		return "", nil
+	case spec.Scope&scopeAdmin != 0:
+		if private {
+			return fmt.Sprintf("Run /%s in a group chat", spec.Name), nil
+		}
+		isAdmin, err := isChatAdmin(command)
+		if err != nil {
+			return "Couldn't check your permissions", err
+		}
+		if !isAdmin {
+			return fmt.Sprintf("Only chat admins can use /%s", spec.Name), nil
+		}
+	case private && spec.Scope&scopePrivate == 0:
+		return fmt.Sprintf("Run /%s in a group chat", spec.Name), nil
+	case !private && spec.Scope&scopeGroup == 0:
+		return fmt.Sprintf("Send /%s to me in private chat", spec.Name), nil
+	}
+	return "", nil
+}
+
+func (spec commandSpec) describe(language string) string {
+	if translation, ok := spec.Translations[language]; ok {
+		return translation
+	}
+	return spec.Description
+}
+
+// Usage line for /help
+func (spec commandSpec) usage() string {
+	line := "/" + spec.Name
+	if spec.Args != "" {
+		line += " " + spec.Args
+	}
+	line += " - " + spec.Description
+
+	switch {
+	case spec.Scope&scopeAdmin != 0:
+		line += " (admins only)"
+	case spec.Scope == scopePrivate:
+		line += " (private chat only)"
+	}
+	return line
+}
+
+func getHelpText(private bool) string {
+	visible := scopeGroup | scopeAdmin
+	if private {
+		visible = scopePrivate
+	}
+
+	lines := []string{"Usage:"}
+	for _, spec := range commands {
+		if spec.Scope&visible != 0 {
+			lines = append(lines, spec.usage())
+		}
+	}
+	return strings.Join(lines, "\n") + "\n" + helpFooter
+}
+
+func help(command Command) error {
+	answer := tgbot.NewMessage(command.ChatID, getHelpText(isPrivateChat(command)))
+	_, err := send(answer, command.ThreadID)
+	if err != nil {
+		logger.Log.Error().Err(err).Send()
+		return err
+	}
+	return nil
+}
+
+func getMenuCommands(scope commandScope, language string) []tgbot.BotCommand {
+	menu := make([]tgbot.BotCommand, 0, len(commands))
+	for _, spec := range commands {
+		if spec.Scope&scope != 0 {
+			menu = append(menu, tgbot.BotCommand{
+				Command:     spec.Name,
+				Description: spec.describe(language),
+			})
+		}
+	}
+	return menu
+}
+
+// Publish command menu to telegram for every scope and language
+func registerCommandMenu() {
+	languages := []string{""}
+	seen := map[string]bool{}
+	for _, spec := range commands {
+		for language := range spec.Translations {
+			if !seen[language] {
+				seen[language] = true
+				languages = append(languages, language)
+			}
+		}
+	}
+
+	menus := []struct {
+		scope    tgbot.BotCommandScope
+		commands commandScope
+	}{
+		{tgbot.NewBotCommandScopeAllPrivateChats(), scopePrivate},
+		{tgbot.NewBotCommandScopeAllGroupChats(), scopeGroup},
+		// Admin menu replaces group menu for admins
+		{tgbot.NewBotCommandScopeAllChatAdministrators(), scopeGroup | scopeAdmin},
+	}
+
+	for _, menu := range menus {
+		for _, language := range languages {
+			config := tgbot.NewSetMyCommandsWithScopeAndLanguage(
+				menu.scope,
+				language,
+				getMenuCommands(menu.commands, language)...,
+			)
+			_, err := bot.Request(config)
+			if err != nil {
+				logger.Log.Error().
+					Err(err).
+					Str("scope", menu.scope.Type).
+					Str("language", language).
+					Msg("Unable to set bot commands")
+			}
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const (
+	dashboardText      = "Your upcoming duties"
+	dashboardDayFormat = "02 Jan, Mon"
+	// How far ahead to look for duties to swap with
+	swapCandidateWeeks = 4
+	// Keyboard size limits, telegram rejects too large keyboards
+	maxDashboardDuties = 20
+	maxSwapCandidates  = 30
+)
+
+func isPrivateChat(command Command) bool {
+	return command.ChatID == command.From.ID
+}
+
+// Title of group chat for dashboard buttons.
+// Falls back to chat ID if telegram doesn't know the chat.
+func getChatTitle(chatID int64) string {
+	chat, err := bot.GetChat(tgbot.ChatInfoConfig{
+		ChatConfig: tgbot.ChatConfig{ChatID: chatID},
+	})
+	if err != nil || chat.Title == "" {
+		return strconv.FormatInt(chatID, 10)
+	}
+	return chat.Title
+}
+
+// Text above dashboard buttons
+func dashboardTitle(duties []assignment.Assignment) string {
+	switch {
+	case len(duties) == 0:
+		return "You have no upcoming duties"
+	case len(duties) > maxDashboardDuties:
+		return fmt.Sprintf("%s, first %d of %d", dashboardText, maxDashboardDuties, len(duties))
+	default:
+		return dashboardText
+	}
+}
+
+func makeDashboardButtons(duties []assignment.Assignment) tgbot.InlineKeyboardMarkup {
+	if len(duties) > maxDashboardDuties {
+		duties = duties[:maxDashboardDuties]
+	}
+	titles := make(map[int64]string)
+	keyboard := make([][]tgbot.InlineKeyboardButton, 0, len(duties)*2)
+	for _, as := range duties {
+		title, ok := titles[as.ChatID]
+		if !ok {
+			title = getChatTitle(as.ChatID)
+			titles[as.ChatID] = title
+		}
+
+		keyboard = append(keyboard,
+			tgbot.NewInlineKeyboardRow(callbackButton(
+				fmt.Sprintf("%s %s%s", as.At.Format(dashboardDayFormat), title, roleSuffix(as)),
+				callback.Data{Action: callback.ActionShow, View: callback.ViewDashboard},
+			)),
+			tgbot.NewInlineKeyboardRow(
+				callbackButton("give away", callback.Data{
+					Action: callback.ActionGiveAway,
+					View:   callback.ViewDashboard,
+					ID:     as.ID,
+				}),
+				callbackButton("swap", callback.Data{
+					Action: callback.ActionSwapFrom,
+					View:   callback.ViewDashboard,
+					ID:     as.ID,
+				}),
+			),
+		)
+	}
+	return tgbot.NewInlineKeyboardMarkup(keyboard...)
+}
+
+func getUpcomingDuties(userID int64) ([]assignment.Assignment, error) {
+	return assignment.AssignmentRepo.GetOperatorAssignments(
+		context.Background(),
+		userID,
+		utils.GetToday(),
+	)
+}
+
+// Personal dashboard with duties in every chat.
+// Works only in private chat with bot.
+func showMyDuties(command Command) error {
+	if !isPrivateChat(command) {
+		reply(command, "Send /my to me in private chat", NoParseMode)
+		return nil
+	}
+
+	duties, err := getUpcomingDuties(command.From.ID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch your duties", NoParseMode)
+		return err
+	}
+	if len(duties) == 0 {
+		reply(command, "You have no upcoming duties", NoParseMode)
+		return nil
+	}
+
+	answer := tgbot.NewMessage(command.ChatID, dashboardTitle(duties))
+	answer.ReplyMarkup = makeDashboardButtons(duties)
+	_, err = send(answer, command.ThreadID)
+	return err
+}
+
+func refreshDashboard(command Command) error {
+	duties, err := getUpcomingDuties(command.From.ID)
+	if err != nil {
+		return err
+	}
+
+	edit := tgbot.NewEditMessageTextAndMarkup(
+		command.ChatID,
+		command.KeyboardID,
+		dashboardTitle(duties),
+		makeDashboardButtons(duties),
+	)
+	_, err = bot.Send(edit)
+	if err != nil {
+		logger.Log.Warn().Stack().Err(err).Send()
+	}
+	return nil
+}
+
+// Get assignment from callback data and make sure
+// it still belongs to the user who pressed the button
+func getOwnDuty(command Command) (assignment.Assignment, error) {
+	as, err := assignment.AssignmentRepo.GetAssignment(context.Background(), command.Callback.ID)
+	if err != nil {
+		return assignment.Assignment{}, err
+	}
+	if as.UserID != command.From.ID || as.At.Before(utils.GetToday()) {
+		return assignment.Assignment{}, assignment.ErrNotFound
+	}
+	return as, nil
+}
+
+func giveAwayDuty(command Command) error {
+	as, err := getOwnDuty(command)
+	switch {
+	case errors.Is(err, assignment.ErrNotFound):
+		return refreshDashboard(command)
+	case err != nil:
+		return err
+	default:
+	}
+
+	change, err := assignment.AssignmentRepo.DeleteAssignment(
+		context.Background(),
+		as.ID,
+		getActor(command),
+	)
+	if err != nil {
+		return err
+	}
+	refreshChatKeyboards(as.ChatID, 0)
+	notifyWatchers(as)
+
+	date := as.At.Format(utils.AssignDateFormat)
+	announceWithUndo(
+		as.ChatID,
+		fmt.Sprintf(
+			"%s gave away duty on %s, try /assign %s",
+			mentionOperator(context.Background(), as),
+			date,
+			date,
+		),
+		HTMLParseMode,
+		change,
+	)
+	return refreshDashboard(command)
+}
+
+// Button per duty of other operators to swap own duty with and back button
+func makeSwapCandidatesButtons(
+	userID int64,
+	as assignment.Assignment,
+	schedule []assignment.Assignment,
+) [][]tgbot.InlineKeyboardButton {
+	keyboard := make([][]tgbot.InlineKeyboardButton, 0, len(schedule)+1)
+	for _, other := range schedule {
+		if other.Operator == "" || other.UserID == userID {
+			continue
+		}
+		if len(keyboard) == maxSwapCandidates {
+			break
+		}
+		keyboard = append(keyboard, tgbot.NewInlineKeyboardRow(
+			callbackButton(
+				fmt.Sprintf("%s %s", other.At.Format(dashboardDayFormat), other.Operator),
+				callback.Data{
+					Action: callback.ActionSwap,
+					View:   callback.ViewDashboard,
+					ID:     as.ID,
+					Date:   other.At,
+				},
+			),
+		))
+	}
+	keyboard = append(keyboard, tgbot.NewInlineKeyboardRow(
+		callbackButton("back", callback.Data{Action: callback.ActionShow, View: callback.ViewDashboard}),
+	))
+	return keyboard
+}
+
+// Show duties of other operators in the same chat to swap with
+func chooseSwapDuty(command Command) error {
+	as, err := getOwnDuty(command)
+	switch {
+	case errors.Is(err, assignment.ErrNotFound):
+		return refreshDashboard(command)
+	case err != nil:
+		return err
+	default:
+	}
+
+	today := utils.GetToday()
+	schedule, err := assignment.AssignmentRepo.GetAssignmentsInRange(
+		context.Background(),
+		today,
+		today.Add(utils.WeekDuration*swapCandidateWeeks),
+		as.ChatID,
+	)
+	if err != nil {
+		return err
+	}
+	keyboard := makeSwapCandidatesButtons(command.From.ID, as, schedule)
+
+	text := fmt.Sprintf("Swap %s in %s with",
+		as.At.Format(dashboardDayFormat),
+		getChatTitle(as.ChatID),
+	)
+	if len(keyboard) == 1 {
+		text = "Nobody to swap with"
+	}
+	edit := tgbot.NewEditMessageTextAndMarkup(
+		command.ChatID,
+		command.KeyboardID,
+		text,
+		tgbot.NewInlineKeyboardMarkup(keyboard...),
+	)
+	_, err = bot.Send(edit)
+	if err != nil {
+		logger.Log.Warn().Stack().Err(err).Send()
+	}
+	return nil
+}
+
+func swapDuty(command Command) error {
+	mine, err := getOwnDuty(command)
+	switch {
+	case errors.Is(err, assignment.ErrNotFound):
+		return refreshDashboard(command)
+	case err != nil:
+		return err
+	default:
+	}
+
+	other, err := assignment.AssignmentRepo.GetAssignmentByDate(
+		context.Background(),
+		command.Callback.Date,
+		mine.ChatID,
+		mine.Role,
+	)
+	if err != nil {
+		return err
+	}
+	if other.Operator == "" || other.UserID == command.From.ID {
+		return refreshDashboard(command)
+	}
+
+	text, err := findSwapAbsence(context.Background(), mine, other)
+	if err != nil {
+		return err
+	}
+	if text == "" {
+		err = requestSwap(context.Background(), command.From, mine, other)
+		if err != nil {
+			return err
+		}
+		text = fmt.Sprintf("Asked %s to swap, waiting for the answer", other.Operator)
+	}
+
+	edit := tgbot.NewEditMessageTextAndMarkup(
+		command.ChatID,
+		command.KeyboardID,
+		text,
+		tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(
+			callbackButton("back", callback.Data{Action: callback.ActionShow, View: callback.ViewDashboard}),
+		)),
+	)
+	_, err = bot.Send(edit)
+	if err != nil {
+		logger.Log.Warn().Stack().Err(err).Send()
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const (
+	historyLimit      = 20
+	historyTimeFormat = "02-01 15:04"
+)
+
+func showHistory(command Command) error {
+	filter := assignment.EventFilter{
+		ChatID: command.ChatID,
+		Limit:  historyLimit,
+	}
+	if strings.TrimSpace(command.Arguments) != "" {
+		date, err := utils.ParseDate(command.Arguments)
+		if err != nil {
+			reply(command, err.Error(), NoParseMode)
+			return err
+		}
+		filter.From = date
+		filter.To = date
+	}
+
+	history, err := assignment.AssignmentRepo.GetAssignmentEvents(context.Background(), filter)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch schedule history", NoParseMode)
+		return err
+	}
+	if len(history) == 0 {
+		reply(command, "No schedule changes yet", NoParseMode)
+		return nil
+	}
+
+	table := utils.NewPrettyTable()
+	for _, event := range history {
+		table.AddRow([]string{
+			event.CreatedAt.Format(historyTimeFormat),
+			event.Kind,
+			event.At.Format(utils.AssignDateFormat),
+			event.Operator,
+			fmt.Sprintf("by %s", event.Actor),
+		})
+	}
+	output, err := table.String()
+	if err != nil {
+		logger.Log.Error().Err(err).Send()
+		return err
+	}
+
+	reply(command, fmt.Sprintf("```\n%s\n```", output), MarkdownParseMode)
+	return nil
+}
@@ -0,0 +1,346 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/alertmanager"
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/incident"
+	"github.com/FedoseevAlex/DutyBot/internal/database/user"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/tasks"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Escalation levels
+const (
+	escalateToOperator = iota
+	// Backup operator or the next one in roster
+	escalateToNextOperator
+	escalateToAdmins
+)
+
+// How far to look for the next operator in roster
+const rosterLookupWeeks = 4
+
+const incidentTimeFormat = "15:04 02-01"
+
+func incidentKeyboard(inc incident.Incident) tgbot.InlineKeyboardMarkup {
+	ack := callbackButton("Ack", callback.Data{
+		Action: callback.ActionAck,
+		View:   callback.ViewIncident,
+		ID:     inc.ID,
+	})
+	resolve := callbackButton("Resolve", callback.Data{
+		Action: callback.ActionResolve,
+		View:   callback.ViewIncident,
+		ID:     inc.ID,
+	})
+
+	switch inc.Status {
+	case incident.StatusOpen:
+		return tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(ack, resolve))
+	case incident.StatusAcknowledged:
+		return tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(resolve))
+	default:
+		return tgbot.InlineKeyboardMarkup{InlineKeyboard: [][]tgbot.InlineKeyboardButton{}}
+	}
+}
+
+func openIncident(ctx context.Context, chatID int64, msg alertmanager.Message, as assignment.Assignment) error {
+	now := time.Now().UTC()
+	inc := incident.Incident{
+		ID:              uuid.New(),
+		ChatID:          chatID,
+		GroupKey:        msg.GroupKey,
+		Title:           msg.Title(),
+		Status:          incident.StatusOpen,
+		Operator:        as.Operator,
+		OperatorID:      as.UserID,
+		EscalationLevel: escalateToOperator,
+		CreatedAt:       now,
+		EscalatedAt:     now,
+	}
+	text := html.EscapeString(msg.Format())
+	if as.Operator == "" {
+		text += "\nNo one is on duty today"
+	} else {
+		text += fmt.Sprintf("\nOn duty: %s", mentionOperator(ctx, as))
+	}
+
+	post := tgbot.NewMessage(chatID, text)
+	post.ParseMode = HTMLParseMode
+	post.ReplyMarkup = incidentKeyboard(inc)
+	// Post first, incident without message could never be acknowledged
+	response, err := send(post, getAnnounceThreadID(chatID))
+	if err != nil {
+		return err
+	}
+
+	inc.MessageID = response.MessageID
+	err = incident.IncidentRepo.AddIncident(ctx, inc)
+	if err != nil {
+		// Buttons of unknown incident would do nothing
+		editKeyboard(chatID, response.MessageID, tgbot.InlineKeyboardMarkup{InlineKeyboard: [][]tgbot.InlineKeyboardButton{}})
+		return err
+	}
+	return nil
+}
+
+func getIncidentFromCallback(command Command) (incident.Incident, error) {
+	inc, err := incident.IncidentRepo.GetIncident(context.Background(), command.Callback.ID)
+	if err != nil {
+		return incident.Incident{}, err
+	}
+	if inc.ChatID != command.ChatID {
+		return incident.Incident{}, incident.ErrNotFound
+	}
+	return inc, nil
+}
+
+func acknowledgeIncident(command Command) error {
+	inc, err := getIncidentFromCallback(command)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("incident", command.Callback.ID.String()).Msg("Unknown incident")
+		return err
+	}
+
+	ok, err := incident.IncidentRepo.Acknowledge(context.Background(), inc.ID, command.From.DisplayName())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	inc.Status = incident.StatusAcknowledged
+
+	updateIncidentKeyboard(inc)
+	replyToIncident(inc, fmt.Sprintf("Acknowledged by %s", command.From.Mention()))
+	return nil
+}
+
+func resolveIncidentCallback(command Command) error {
+	inc, err := getIncidentFromCallback(command)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("incident", command.Callback.ID.String()).Msg("Unknown incident")
+		return err
+	}
+	return resolveIncident(context.Background(), inc, command.From.DisplayName(), command.From.Mention())
+}
+
+// Resolve incident on behalf of by. Mention is used in chat
+// and should be formatted for HTMLParseMode.
+func resolveIncident(ctx context.Context, inc incident.Incident, by string, mention string) error {
+	ok, err := incident.IncidentRepo.Resolve(ctx, inc.ID, by)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	inc.Status = incident.StatusResolved
+
+	updateIncidentKeyboard(inc)
+	replyToIncident(inc, fmt.Sprintf("Resolved by %s", mention))
+	return nil
+}
+
+func updateIncidentKeyboard(inc incident.Incident) {
+	edit := tgbot.NewEditMessageReplyMarkup(inc.ChatID, inc.MessageID, incidentKeyboard(inc))
+	_, err := bot.Send(edit)
+	if err != nil {
+		logger.Log.Warn().Stack().Err(err).Send()
+	}
+}
+
+// Reply to incident message. Text is sent with HTMLParseMode.
+func replyToIncident(inc incident.Incident, text string) {
+	msg := tgbot.NewMessage(inc.ChatID, text)
+	msg.ParseMode = HTMLParseMode
+	msg.ReplyToMessageID = inc.MessageID
+	_, err := bot.Send(msg)
+	if err != nil {
+		logger.Log.Error().Err(err).Send()
+	}
+}
+
+// Secondary operator on duty now unless it is the given one
+func backupOperator(ctx context.Context, chatID int64, current int64) (assignment.Assignment, bool, error) {
+	backup, err := assignment.AssignmentRepo.GetAssignmentAt(ctx, utils.GetNow(), chatID, assignment.RoleSecondary)
+	if err != nil {
+		return assignment.Assignment{}, false, err
+	}
+	if backup.Operator == "" || backup.UserID == current {
+		return assignment.Assignment{}, false, nil
+	}
+	return backup, true, nil
+}
+
+// Find closest assignment in roster after today
+// of operator who is not the given one.
+func nextRosterOperator(ctx context.Context, chatID int64, current int64) (assignment.Assignment, bool, error) {
+	today := utils.GetToday()
+	schedule, err := assignment.AssignmentRepo.GetAssignmentSchedule(
+		ctx,
+		today.Add(utils.WeekDuration*rosterLookupWeeks),
+		chatID,
+	)
+	if err != nil {
+		return assignment.Assignment{}, false, err
+	}
+
+	// Schedule is ordered from the latest date
+	for i := len(schedule) - 1; i >= 0; i-- {
+		as := schedule[i]
+		if !as.At.After(today) || as.Operator == "" || as.UserID == current || as.IsBackup() {
+			continue
+		}
+		return as, true, nil
+	}
+	return assignment.Assignment{}, false, nil
+}
+
+func getChatAdmins(chatID int64) ([]user.User, error) {
+	members, err := bot.GetChatAdministrators(tgbot.ChatAdministratorsConfig{
+		ChatConfig: tgbot.ChatConfig{ChatID: chatID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	admins := make([]user.User, 0, len(members))
+	for _, member := range members {
+		if member.User == nil || member.User.IsBot {
+			continue
+		}
+		admins = append(admins, userFromTelegram(member.User))
+	}
+	return admins, nil
+}
+
+func escalateIncident(ctx context.Context, inc incident.Incident, timeout time.Duration) error {
+	level := inc.EscalationLevel + 1
+	var names, mentions []string
+	var operatorID int64
+
+	if level == escalateToNextOperator {
+		next, ok, err := backupOperator(ctx, inc.ChatID, inc.OperatorID)
+		if err == nil && !ok {
+			next, ok, err = nextRosterOperator(ctx, inc.ChatID, inc.OperatorID)
+		}
+		if err != nil {
+			return err
+		}
+		if ok {
+			names = []string{next.Operator}
+			operatorID = next.UserID
+			mentions = []string{mentionOperator(ctx, next)}
+		} else {
+			level = escalateToAdmins
+		}
+	}
+
+	if level >= escalateToAdmins {
+		// Keep pinging admins until somebody reacts
+		level = escalateToAdmins
+		admins, err := getChatAdmins(inc.ChatID)
+		if err != nil {
+			return err
+		}
+		for _, admin := range admins {
+			names = append(names, admin.DisplayName())
+			mentions = append(mentions, admin.Mention())
+		}
+	}
+
+	err := incident.IncidentRepo.Escalate(ctx, inc.ID, level, strings.Join(names, ", "), operatorID)
+	if err != nil {
+		return err
+	}
+
+	replyToIncident(inc, fmt.Sprintf(
+		"'%s' is not acknowledged for %s. Escalating to %s",
+		html.EscapeString(inc.Title),
+		timeout,
+		strings.Join(mentions, " "),
+	))
+	return nil
+}
+
+func escalateIncidentsTask() {
+	logger.Log.Debug().Msg("Start incidents escalation")
+	ctx := context.Background()
+	timeout := viper.GetDuration("IncidentEscalationTimeout")
+
+	incidents, err := incident.IncidentRepo.GetUnacknowledgedIncidents(ctx, time.Now().UTC().Add(-timeout))
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Msg("escalateIncidentsTask job failed to get incidents")
+		return
+	}
+
+	for _, inc := range incidents {
+		err := escalateIncident(ctx, inc, timeout)
+		if err != nil {
+			logger.Log.Error().
+				Err(err).
+				Str("incident", inc.ID.String()).
+				Msg("escalateIncidentsTask job failed to escalate incident")
+		}
+	}
+}
+
+func scheduleEscalationTask() {
+	_, err := tasks.AddTask(viper.GetString("IncidentEscalationSchedule"), escalateIncidentsTask)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Stack().
+			Msg("Unable to schedule task")
+	}
+}
+
+func listIncidents(command Command) error {
+	incidents, err := incident.IncidentRepo.GetActiveIncidents(context.Background(), command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch incidents", NoParseMode)
+		return err
+	}
+	if len(incidents) == 0 {
+		reply(command, "No open incidents", NoParseMode)
+		return nil
+	}
+
+	table := utils.NewPrettyTable()
+	for _, inc := range incidents {
+		assignee := inc.Operator
+		if inc.Status == incident.StatusAcknowledged {
+			assignee = inc.AcknowledgedBy
+		}
+		table.AddRow([]string{
+			inc.CreatedAt.Format(incidentTimeFormat),
+			inc.Status,
+			inc.Title,
+			assignee,
+		})
+	}
+	output, err := table.String()
+	if err != nil {
+		logger.Log.Error().Err(err).Send()
+		return err
+	}
+
+	reply(command, output, NoParseMode)
+	return nil
+}
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const (
+	// Results depend on user and schedule, so they are cached briefly
+	inlineCacheSeconds = 60
+	// Telegram limit for answerInlineQuery
+	maxInlineResults = 50
+)
+
+func getOperatorText(chatID int64) (string, error) {
+	as, err := assignment.AssignmentRepo.GetAssignmentAt(
+		context.Background(),
+		utils.GetNow(),
+		chatID,
+		assignment.RolePrimary,
+	)
+	if err != nil {
+		return "", err
+	}
+	if as.Operator == "" {
+		return "No one is on duty now", nil
+	}
+	return mentionOperator(context.Background(), as), nil
+}
+
+func getNextDutyText(userID int64) (string, error) {
+	duties, err := getUpcomingDuties(userID)
+	if err != nil {
+		return "", err
+	}
+	if len(duties) == 0 {
+		return "No upcoming duties", nil
+	}
+	next := duties[0]
+	return fmt.Sprintf(
+		"Next duty: %s in %s",
+		next.At.Format(utils.HumanDateFormat),
+		html.EscapeString(getChatTitle(next.ChatID)),
+	), nil
+}
+
+// Schedule of every chat user is assigned in, filtered by chat title
+func getInlineResults(userID int64, query string) ([]interface{}, error) {
+	results := make([]interface{}, 0)
+
+	text, err := getNextDutyText(userID)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, tgbot.NewInlineQueryResultArticleHTML("next", "My next duty", text))
+
+	chats, err := assignment.AssignmentRepo.GetOperatorChats(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	for _, chatID := range chats {
+		title := getChatTitle(chatID)
+		if query != "" && !strings.Contains(strings.ToLower(title), query) {
+			continue
+		}
+
+		text, err := getOperatorText(chatID)
+		if err != nil {
+			return nil, err
+		}
+		operator := tgbot.NewInlineQueryResultArticleHTML(
+			fmt.Sprintf("operator:%d", chatID),
+			"Today's operator",
+			text,
+		)
+		operator.Description = title
+		results = append(results, operator)
+
+		table, err := getAssignmentsTable(chatID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if table == "" {
+			table = "Nothing to show"
+		}
+		week := tgbot.NewInlineQueryResultArticleHTML(
+			fmt.Sprintf("week:%d", chatID),
+			"This week",
+			fmt.Sprintf("<pre>%s</pre>", html.EscapeString(table)),
+		)
+		week.Description = title
+		results = append(results, week)
+	}
+	return results, nil
+}
+
+func processInlineQuery(query *tgbot.InlineQuery) error {
+	results, err := getInlineResults(query.From.ID, query.Query)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		results = []interface{}{}
+	}
+	if len(results) > maxInlineResults {
+		results = results[:maxInlineResults]
+	}
+
+	_, err = bot.Request(tgbot.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     inlineCacheSeconds,
+		IsPersonal:    true,
+	})
+	return err
+}
@@ -0,0 +1,329 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/redemption"
+	"github.com/FedoseevAlex/DutyBot/internal/database/user"
+	"github.com/FedoseevAlex/DutyBot/internal/ledger"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const ledgerUsage = "Usage: /ledger [DD-MM-YYYY], /ledger redeem @user N [DD-MM-YYYY] [note] " +
+	"or /ledger unredeem @user DD-MM-YYYY"
+
+func getCompensationRates() ledger.Rates {
+	return ledger.Rates{
+		Weekend: viper.GetInt("CompensationWeekend"),
+		Holiday: viper.GetInt("CompensationHoliday"),
+	}
+}
+
+// Compensation balances of chat operators on given day.
+// Balances run through the whole history of chat,
+// duties are counted once they are done.
+func getBalances(chatID int64, day time.Time) ([]ledger.Balance, error) {
+	ctx := context.Background()
+	until := day
+	if today := utils.GetToday(); today.Before(until) {
+		until = today
+	}
+
+	assignments, err := assignment.AssignmentRepo.GetAssignmentsInRange(ctx, time.Time{}, until, chatID)
+	if err != nil {
+		return nil, err
+	}
+	redemptions, err := redemption.RedemptionRepo.GetRedemptions(ctx, chatID, time.Time{}, day)
+	if err != nil {
+		return nil, err
+	}
+
+	workingDays := calendar.TimeSet{}
+	if len(assignments) != 0 {
+		// Assignments are ordered by day, weekly duties start on their first day
+		workingDays, err = calendar.GetCachedWorkingDays(utils.GetDate(assignments[0].At), until)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errNoCalendar, err)
+		}
+	}
+
+	balances := ledger.Compute(assignments, redemptions, workingDays, getCompensationRates(), until)
+	// Operators without duties are known only by ID
+	for i, b := range balances {
+		if b.Operator != "" {
+			continue
+		}
+		u, err := user.UserRepo.GetUser(ctx, b.UserID)
+		if err != nil {
+			balances[i].Operator = strconv.FormatInt(b.UserID, 10)
+			continue
+		}
+		balances[i].Operator = u.DisplayName()
+	}
+	return balances, nil
+}
+
+// Ledger can't tell public holidays from working days without calendar
+var errNoCalendar = errors.New("production calendar is unavailable")
+
+func replyBalancesError(command Command, err error) {
+	if errors.Is(err, errNoCalendar) {
+		reply(command, "Production calendar is unavailable, try again later", NoParseMode)
+		return
+	}
+	reply(command, "Couldn't compute ledger", NoParseMode)
+}
+
+func getLedgerTable(balances []ledger.Balance) (string, error) {
+	table := utils.NewPrettyTable()
+	table.AddRow([]string{"operator", "weekends", "holidays", "earned", "taken", "left"})
+	for _, b := range balances {
+		table.AddRow([]string{
+			b.Operator,
+			strconv.Itoa(b.Weekends),
+			strconv.Itoa(b.Holidays),
+			strconv.Itoa(b.Earned),
+			strconv.Itoa(b.Redeemed),
+			strconv.Itoa(b.Left),
+		})
+	}
+	return table.String()
+}
+
+// /ledger [DD-MM-YYYY] shows days off earned for weekend and holiday duties
+func showLedger(command Command) error {
+	args := strings.Fields(command.Arguments)
+	if len(args) != 0 {
+		switch args[0] {
+		case "redeem":
+			return redeemDaysOff(command, args[1:])
+		case "unredeem":
+			return unredeemDaysOff(command, args[1:])
+		}
+	}
+	if len(args) > 1 {
+		reply(command, ledgerUsage, NoParseMode)
+		return nil
+	}
+
+	day := utils.GetToday()
+	if len(args) == 1 {
+		date, err := utils.ParseDate(args[0])
+		if err != nil {
+			reply(command, ledgerUsage, NoParseMode)
+			return nil
+		}
+		day = date
+	}
+
+	balances, err := getBalances(command.ChatID, day)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		replyBalancesError(command, err)
+		return err
+	}
+	if len(balances) == 0 {
+		reply(
+			command,
+			fmt.Sprintf("No days off earned by %s", day.Format(utils.AssignDateFormat)),
+			NoParseMode,
+		)
+		return nil
+	}
+
+	output, err := getLedgerTable(balances)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return err
+	}
+	reply(
+		command,
+		fmt.Sprintf("```\n%s\n%s\n```", day.Format(utils.AssignDateFormat), output),
+		MarkdownParseMode,
+	)
+	return nil
+}
+
+// Parse N [DD-MM-YYYY] [note] of /ledger redeem.
+// Days off are taken today unless date is given.
+func parseRedemption(command Command, args []string) (redemption.Redemption, error) {
+	days, err := strconv.Atoi(args[0])
+	if err != nil || days < 1 {
+		return redemption.Redemption{}, errors.New("Number of days should be positive")
+	}
+
+	takenOn := utils.GetToday()
+	note := args[1:]
+	if len(note) != 0 {
+		if date, err := utils.ParseDate(note[0]); err == nil {
+			takenOn = date
+			note = note[1:]
+		}
+	}
+	return redemption.Redemption{
+		ID:        uuid.New(),
+		ChatID:    command.ChatID,
+		Days:      days,
+		TakenOn:   takenOn,
+		Note:      strings.Join(note, " "),
+		Actor:     command.From.DisplayName(),
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// Days off user has left when taking them on the day.
+// Redemption counts against balance on its day, but not earlier than today
+// so that it doesn't spend days off of duties done by then.
+func getDaysOffLeft(chatID, userID int64, takenOn time.Time) (int, error) {
+	day := takenOn
+	if today := utils.GetToday(); day.Before(today) {
+		day = today
+	}
+	balances, err := getBalances(chatID, day)
+	if err != nil {
+		return 0, err
+	}
+	return ledger.Find(balances, userID).Left, nil
+}
+
+// /ledger redeem @user N [DD-MM-YYYY] [note] records days off taken
+func redeemDaysOff(command Command, args []string) error {
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can record days off", NoParseMode)
+		return nil
+	}
+
+	if len(args) < 2 || !strings.HasPrefix(args[0], "@") {
+		reply(command, ledgerUsage, NoParseMode)
+		return nil
+	}
+	red, err := parseRedemption(command, args[1:])
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return nil
+	}
+
+	userID, err := lookupUsername(strings.TrimPrefix(args[0], "@"))
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+	u, err := user.UserRepo.GetUser(context.Background(), userID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return err
+	}
+	red.UserID = u.ID
+
+	left, err := getDaysOffLeft(command.ChatID, u.ID, red.TakenOn)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		replyBalancesError(command, err)
+		return err
+	}
+	if red.Days > left {
+		reply(command, fmt.Sprintf("%s has only %d days off left", u.DisplayName(), left), NoParseMode)
+		return nil
+	}
+
+	return saveRedemption(command, red, u, left)
+}
+
+// Record days off and tell how many are left
+func saveRedemption(command Command, red redemption.Redemption, u user.User, left int) error {
+	err := redemption.RedemptionRepo.AddRedemption(context.Background(), red)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to record days off", NoParseMode)
+		return err
+	}
+	reply(
+		command,
+		fmt.Sprintf(
+			"%s took %d days off on %s, %d left",
+			u.DisplayName(),
+			red.Days,
+			red.TakenOn.Format(utils.AssignDateFormat),
+			left-red.Days,
+		),
+		NoParseMode,
+	)
+	return nil
+}
+
+// /ledger unredeem @user DD-MM-YYYY deletes days off recorded by mistake
+func unredeemDaysOff(command Command, args []string) error {
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can delete days off", NoParseMode)
+		return nil
+	}
+
+	if len(args) != 2 || !strings.HasPrefix(args[0], "@") {
+		reply(command, ledgerUsage, NoParseMode)
+		return nil
+	}
+	takenOn, err := utils.ParseDate(args[1])
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+	userID, err := lookupUsername(strings.TrimPrefix(args[0], "@"))
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	ctx := context.Background()
+	redemptions, err := redemption.RedemptionRepo.GetRedemptions(ctx, command.ChatID, takenOn, takenOn)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch days off", NoParseMode)
+		return err
+	}
+	days := 0
+	for _, r := range redemptions {
+		if r.UserID != userID {
+			continue
+		}
+		err = redemption.RedemptionRepo.DeleteRedemption(ctx, r.ID)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+			reply(command, "Failed to delete days off", NoParseMode)
+			return err
+		}
+		days += r.Days
+	}
+	if days == 0 {
+		reply(command, fmt.Sprintf("%s took no days off on %s", args[0], args[1]), NoParseMode)
+		return nil
+	}
+	reply(
+		command,
+		fmt.Sprintf("%d days off of %s on %s are deleted", days, args[0], takenOn.Format(utils.AssignDateFormat)),
+		NoParseMode,
+	)
+	return nil
+}
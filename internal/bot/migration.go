@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+)
+
+// When group is upgraded to supergroup telegram sends
+// migrate_to_chat_id to the old group and
+// migrate_from_chat_id to the new supergroup.
+func getChatMigration(msg *tgbot.Message) (from int64, to int64, ok bool) {
+	switch {
+	case msg.MigrateToChatID != 0:
+		return msg.Chat.ID, msg.MigrateToChatID, true
+	case msg.MigrateFromChatID != 0:
+		return msg.MigrateFromChatID, msg.Chat.ID, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// Check if request failed because chat is a supergroup now
+func migratedTo(err error) (int64, bool) {
+	var tgErr *tgbot.Error
+	if errors.As(err, &tgErr) && tgErr.MigrateToChatID != 0 {
+		return tgErr.MigrateToChatID, true
+	}
+	return 0, false
+}
+
+func migrateChat(from, to int64) error {
+	// Old keyboards stay in the old group and can't be edited anymore
+	keyboards, err := chat.ChatRepo.GetKeyboards(context.Background(), from)
+	if err != nil {
+		return err
+	}
+	for _, keyboard := range keyboards {
+		forgetKeyboard(keyboard)
+	}
+
+	moved, err := chat.ChatRepo.MigrateChat(context.Background(), from, to)
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Info().
+		Int64("from", from).
+		Int64("to", to).
+		Int64("rows", moved).
+		Msg("Chat migrated to supergroup")
+	return nil
+}
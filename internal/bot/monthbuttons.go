@@ -0,0 +1,254 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Day marks of month grid
+const (
+	markTaken   = "•"
+	markHoliday = "·"
+)
+
+// State of month keyboard. Zero Selected and SwapFrom mean
+// nothing is selected and no swap is in progress.
+type monthView struct {
+	Month    time.Time
+	Selected time.Time
+	SwapFrom time.Time
+}
+
+func getFirstOfMonth(t time.Time) time.Time {
+	year, month, _ := t.Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func monthCallback(action callback.Action, date time.Time) callback.Data {
+	return callback.Data{Action: action, View: callback.ViewMonth, Date: date}
+}
+
+// Button that does nothing (headers and padding)
+func ignoreButton(text string) tgbot.InlineKeyboardButton {
+	return callbackButton(text, callback.Data{Action: callback.ActionIgnore})
+}
+
+func getMonthData(chatID int64, first, last time.Time) (map[time.Time]assignment.Assignment, calendar.TimeSet) {
+	duties := make(map[time.Time]assignment.Assignment)
+	schedule, err := assignment.AssignmentRepo.GetAssignmentsInRange(context.Background(), first, last, chatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+	}
+	for _, as := range schedule {
+		if as.IsBackup() {
+			continue
+		}
+		duties[utils.GetDate(as.At)] = as
+	}
+
+	return duties, getWorkingDays(first, last)
+}
+
+// Working days between first and last inclusive.
+// Weekends are considered holidays if calendar is unavailable,
+// use calendar.GetCachedWorkingDays where public holidays matter.
+func getWorkingDays(first, last time.Time) calendar.TimeSet {
+	workingDays, err := calendar.GetCachedWorkingDays(first, last)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Unable to get working days, using weekends")
+		workingDays = calendar.TimeSet{}
+		for date := first; !date.After(last); date = date.Add(utils.DayDuration) {
+			if date.Weekday() != time.Saturday && date.Weekday() != time.Sunday {
+				workingDays.Add(date)
+			}
+		}
+	}
+	return workingDays
+}
+
+// Day of month grid, marked if it is a holiday or taken.
+// While choosing day to swap with only taken upcoming days can be pressed.
+func makeMonthDayButton(
+	date time.Time,
+	view monthView,
+	taken, working bool,
+	today time.Time,
+) tgbot.InlineKeyboardButton {
+	label := strconv.Itoa(date.Day())
+	switch {
+	case !working:
+		label += markHoliday
+	case taken:
+		label += markTaken
+	}
+
+	data := monthCallback(callback.ActionSelect, date)
+	if !view.SwapFrom.IsZero() {
+		data = callback.Data{Action: callback.ActionIgnore}
+		if taken && !date.Equal(view.SwapFrom) && !date.Before(today) {
+			data = monthCallback(callback.ActionSwap, view.SwapFrom)
+			data.SecondDate = date
+		}
+	}
+	return callbackButton(label, data)
+}
+
+func makeMonthButtons(chatID int64, view monthView) tgbot.InlineKeyboardMarkup {
+	first := getFirstOfMonth(view.Month)
+	last := first.AddDate(0, 1, -1)
+	duties, workingDays := getMonthData(chatID, first, last)
+	today := utils.GetToday()
+
+	keyboard := [][]tgbot.InlineKeyboardButton{
+		tgbot.NewInlineKeyboardRow(
+			callbackButton("<", monthCallback(callback.ActionShow, first.AddDate(0, -1, 0))),
+			ignoreButton(first.Format("January 2006")),
+			callbackButton(">", monthCallback(callback.ActionShow, first.AddDate(0, 1, 0))),
+		),
+	}
+
+	header := make([]tgbot.InlineKeyboardButton, 0, utils.DaysInWeek)
+	for _, day := range []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"} {
+		header = append(header, ignoreButton(day))
+	}
+	keyboard = append(keyboard, header)
+
+	for weekStart := utils.GetStartOfWeek(first); !weekStart.After(last); weekStart = weekStart.Add(utils.WeekDuration) {
+		row := make([]tgbot.InlineKeyboardButton, 0, utils.DaysInWeek)
+		for i := 0; i < utils.DaysInWeek; i++ {
+			date := weekStart.AddDate(0, 0, i)
+			if date.Month() != first.Month() {
+				row = append(row, ignoreButton(" "))
+				continue
+			}
+
+			_, taken := duties[date]
+			_, working := workingDays[date]
+			row = append(row, makeMonthDayButton(date, view, taken, working, today))
+		}
+		keyboard = append(keyboard, row)
+	}
+
+	switch {
+	case !view.SwapFrom.IsZero():
+		keyboard = append(keyboard, tgbot.NewInlineKeyboardRow(
+			ignoreButton(fmt.Sprintf("swap %s with...", view.SwapFrom.Format(dashboardDayFormat))),
+			callbackButton("cancel", monthCallback(callback.ActionSelect, view.SwapFrom)),
+		))
+	case !view.Selected.IsZero():
+		as, taken := duties[view.Selected]
+		_, working := workingDays[view.Selected]
+		keyboard = append(keyboard, makeDayDetails(view.Selected, as, taken, working, today))
+	}
+
+	return tgbot.NewInlineKeyboardMarkup(keyboard...)
+}
+
+// Row with selected day and actions available for it
+func makeDayDetails(
+	date time.Time,
+	as assignment.Assignment,
+	taken bool,
+	working bool,
+	today time.Time,
+) []tgbot.InlineKeyboardButton {
+	label := date.Format(dashboardDayFormat)
+	switch {
+	case !working:
+		return tgbot.NewInlineKeyboardRow(ignoreButton(label + " day off"))
+	case taken:
+		label += " " + as.Operator
+	}
+	if date.Before(today) {
+		return tgbot.NewInlineKeyboardRow(ignoreButton(label))
+	}
+
+	if !taken {
+		return tgbot.NewInlineKeyboardRow(
+			ignoreButton(label),
+			callbackButton("assign", monthCallback(callback.ActionAssign, date)),
+		)
+	}
+	return tgbot.NewInlineKeyboardRow(
+		ignoreButton(label),
+		callbackButton("reset", monthCallback(callback.ActionReset, date)),
+		callbackButton("swap", monthCallback(callback.ActionSwapFrom, date)),
+	)
+}
+
+func showMonthButtons(command Command, arguments string) error {
+	month := utils.GetToday()
+	if arguments != "" {
+		var err error
+		month, err = utils.ParseDate(arguments)
+		if err != nil {
+			reply(command, err.Error(), NoParseMode)
+			return err
+		}
+	}
+
+	keyboard := makeMonthButtons(command.ChatID, monthView{Month: month})
+	sendKeyboard(
+		command.ChatID,
+		command.ThreadID,
+		chat.KeyboardMonth,
+		getFirstOfMonth(month),
+		keyboard,
+	)
+	return nil
+}
+
+func editMonthKeyboard(command Command, view monthView) {
+	editKeyboard(command.ChatID, command.KeyboardID, makeMonthButtons(command.ChatID, view))
+
+	err := chat.ChatRepo.SetKeyboardShownFrom(
+		context.Background(),
+		command.ChatID,
+		command.KeyboardID,
+		getFirstOfMonth(view.Month),
+	)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+	}
+}
+
+// Handle month keyboard callbacks. Every action redraws keyboard
+// around the day it was made for.
+func processMonthCallback(command Command) error {
+	date := command.Callback.Date
+	if date.IsZero() {
+		return fmt.Errorf("no date in month callback %d", command.Callback.Action)
+	}
+
+	var err error
+	view := monthView{Month: date, Selected: date}
+	switch command.Callback.Action {
+	case callback.ActionShow:
+		view.Selected = time.Time{}
+	case callback.ActionSelect:
+	case callback.ActionAssign:
+		err = assign(command)
+	case callback.ActionReset:
+		err = resetAssign(command)
+	case callback.ActionSwapFrom:
+		view.SwapFrom = date
+	case callback.ActionSwap:
+		err = swapAssign(command)
+	default:
+		return fmt.Errorf("unknown month callback %d", command.Callback.Action)
+	}
+
+	editMonthKeyboard(command, view)
+	return err
+}
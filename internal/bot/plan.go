@@ -0,0 +1,384 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/absence"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/preference"
+	"github.com/FedoseevAlex/DutyBot/internal/database/shift"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/scheduler"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Warning about plan of search that was cut short
+const truncatedPlanNote = "Search was cut short, this is the best plan found but a fairer one might exist."
+
+// Operators assigned in chat during this period form its roster
+const rosterHistory = 90 * utils.DayDuration
+
+// Month like 11-2026
+var monthRegexp = regexp.MustCompile(`^([0-9]{1,2})[-./]([0-9]{4})$`)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+const preferUsage = "Usage: /prefer [avoid mon,fri | max N | no-consecutive | no-after-holiday | reset]"
+
+func parseWeekdays(raw string) ([]time.Weekday, error) {
+	weekdays := make([]time.Weekday, 0)
+	if raw == "none" {
+		return weekdays, nil
+	}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if len(name) > 3 {
+			name = name[:3]
+		}
+		weekday, ok := weekdayNames[name]
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not a weekday, try mon,tue,...", name)
+		}
+		weekdays = append(weekdays, weekday)
+	}
+	return weekdays, nil
+}
+
+func formatPreference(pref preference.Preference) string {
+	lines := make([]string, 0, 4)
+	if weekdays := pref.Weekdays(); len(weekdays) != 0 {
+		names := make([]string, 0, len(weekdays))
+		for _, weekday := range weekdays {
+			names = append(names, weekday.String()[:3])
+		}
+		lines = append(lines, "avoid "+strings.Join(names, ","))
+	}
+	if pref.MaxPerMonth > 0 {
+		lines = append(lines, fmt.Sprintf("at most %d duties per month", pref.MaxPerMonth))
+	}
+	if pref.NoConsecutive {
+		lines = append(lines, "not two days in a row")
+	}
+	if pref.NoAfterHoliday {
+		lines = append(lines, "not the day after a holiday duty")
+	}
+	if len(lines) == 0 {
+		return "No preferences"
+	}
+	return "Preferences:\n" + strings.Join(lines, "\n")
+}
+
+// /prefer shows or changes scheduling preferences of operator in chat
+func prefer(command Command) error {
+	pref, err := preference.PreferenceRepo.GetPreference(context.Background(), command.ChatID, command.From.ID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch your preferences", NoParseMode)
+		return err
+	}
+
+	args := strings.Fields(command.Arguments)
+	if len(args) == 0 {
+		reply(command, formatPreference(pref), NoParseMode)
+		return nil
+	}
+
+	switch {
+	case args[0] == "avoid" && len(args) == 2:
+		weekdays, err := parseWeekdays(args[1])
+		if err != nil {
+			reply(command, err.Error(), NoParseMode)
+			return err
+		}
+		pref.SetWeekdays(weekdays)
+	case args[0] == "max" && len(args) == 2:
+		limit, err := strconv.Atoi(args[1])
+		if err != nil || limit < 0 {
+			reply(command, "Duties limit should be a number, 0 means no limit", NoParseMode)
+			return nil
+		}
+		pref.MaxPerMonth = limit
+	case args[0] == "no-consecutive":
+		pref.NoConsecutive = true
+	case args[0] == "no-after-holiday":
+		pref.NoAfterHoliday = true
+	case args[0] == "reset":
+		pref = preference.DefaultPreference(command.ChatID, command.From.ID)
+	default:
+		reply(command, preferUsage, NoParseMode)
+		return nil
+	}
+
+	err = preference.PreferenceRepo.SavePreference(context.Background(), pref)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save your preferences", NoParseMode)
+		return err
+	}
+	reply(command, formatPreference(pref), NoParseMode)
+	return nil
+}
+
+// Parse MM-YYYY or any date of month. Current month by default.
+func parseMonth(raw string) (time.Time, error) {
+	if raw == "" {
+		return getFirstOfMonth(utils.GetToday()), nil
+	}
+	if match := monthRegexp.FindStringSubmatch(raw); match != nil {
+		month, _ := strconv.Atoi(match[1])
+		year, _ := strconv.Atoi(match[2])
+		if month < 1 || month > 12 {
+			return time.Time{}, fmt.Errorf("'%s' is not a month, try MM-YYYY", raw)
+		}
+		return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	date, err := utils.ParseDate(raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return getFirstOfMonth(date), nil
+}
+
+// Build scheduling problem for the rest of month from chat roster,
+// preferences, absences and existing assignments
+func buildPlanProblem(chatID int64, month time.Time) (scheduler.Problem, map[int64]string, error) {
+	ctx := context.Background()
+	last := month.AddDate(0, 1, -1)
+	first := month
+	if today := utils.GetToday(); today.After(first) {
+		first = today
+	}
+
+	// Day before month matters for preferences about previous day
+	duties, workingDays := getMonthData(chatID, month.Add(-utils.DayDuration), last)
+
+	problem := scheduler.Problem{}
+	for date, as := range duties {
+		problem.Duties = append(problem.Duties, scheduler.Duty{OperatorID: as.UserID, Day: date})
+	}
+	for date := month.Add(-utils.DayDuration); !date.After(last); date = date.Add(utils.DayDuration) {
+		_, working := workingDays[date]
+		_, taken := duties[date]
+		switch {
+		case !working:
+			problem.Holidays = append(problem.Holidays, date)
+		case !taken && !date.Before(first):
+			problem.Days = append(problem.Days, date)
+		}
+	}
+
+	roster, err := assignment.AssignmentRepo.GetChatOperators(ctx, chatID, utils.GetToday().Add(-rosterHistory))
+	if err != nil {
+		return scheduler.Problem{}, nil, err
+	}
+	prefs, err := preference.PreferenceRepo.GetPreferences(ctx, chatID)
+	if err != nil {
+		return scheduler.Problem{}, nil, err
+	}
+	absences, err := absence.AbsenceRepo.GetAbsences(ctx, first, last)
+	if err != nil {
+		return scheduler.Problem{}, nil, err
+	}
+
+	return problem, addPlanOperators(&problem, roster, prefs, absences), nil
+}
+
+// Add roster with preferences and absences to the problem.
+// Returns names of operators by ID.
+func addPlanOperators(
+	problem *scheduler.Problem,
+	roster []assignment.Operator,
+	prefs []preference.Preference,
+	absences []absence.Absence,
+) map[int64]string {
+	operators := make(map[int64]*scheduler.Operator, len(roster))
+	names := make(map[int64]string, len(roster))
+	for _, op := range roster {
+		problem.Operators = append(problem.Operators, scheduler.Operator{ID: op.UserID, Name: op.Name})
+		names[op.UserID] = op.Name
+	}
+	for i := range problem.Operators {
+		operators[problem.Operators[i].ID] = &problem.Operators[i]
+	}
+	for _, pref := range prefs {
+		if op, ok := operators[pref.UserID]; ok {
+			op.Preferences = scheduler.Preferences{
+				AvoidWeekdays:  pref.Weekdays(),
+				MaxPerMonth:    pref.MaxPerMonth,
+				NoConsecutive:  pref.NoConsecutive,
+				NoAfterHoliday: pref.NoAfterHoliday,
+			}
+		}
+	}
+	for _, a := range absences {
+		if op, ok := operators[a.UserID]; ok {
+			op.Absences = append(op.Absences, scheduler.Period{From: a.StartsOn, To: a.EndsOn})
+		}
+	}
+	return names
+}
+
+func getPlanTable(plan scheduler.Plan, names map[int64]string) (string, error) {
+	type row struct {
+		day      time.Time
+		operator string
+	}
+	rows := make([]row, 0, len(plan.Duties)+len(plan.Unfilled))
+	for _, duty := range plan.Duties {
+		rows = append(rows, row{duty.Day, names[duty.OperatorID]})
+	}
+	for _, day := range plan.Unfilled {
+		rows = append(rows, row{day, "nobody"})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].day.Before(rows[j].day) })
+
+	table := utils.NewPrettyTable()
+	for _, r := range rows {
+		table.AddRow([]string{r.operator, r.day.Format(utils.HumanDateFormat)})
+	}
+	return table.String()
+}
+
+// Month argument of /plan and whether the plan should be applied
+func cutApply(args []string) (string, bool) {
+	apply := len(args) != 0 && args[len(args)-1] == "apply"
+	if apply {
+		args = args[:len(args)-1]
+	}
+	return strings.Join(args, " "), apply
+}
+
+// /plan month [MM-YYYY] [apply] previews or assigns fair plan for month
+func planSchedule(command Command) error {
+	args := strings.Fields(command.Arguments)
+	if len(args) == 0 || args[0] != "month" {
+		reply(command, "Usage: /plan month [MM-YYYY] [apply]", NoParseMode)
+		return nil
+	}
+	rawMonth, apply := cutApply(args[1:])
+
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can plan schedule", NoParseMode)
+		return nil
+	}
+
+	month, err := parseMonth(rawMonth)
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+	templates, err := getShiftTemplates(command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return err
+	}
+	if len(templates) != 0 || isWeeklyChat(command.ChatID) {
+		reply(command, "Only daily duties can be planned", NoParseMode)
+		return nil
+	}
+
+	problem, names, err := buildPlanProblem(command.ChatID, month)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't collect data for the plan", NoParseMode)
+		return err
+	}
+	if len(problem.Operators) == 0 {
+		reply(command, "Nobody was on duty here recently, nobody to plan for", NoParseMode)
+		return nil
+	}
+	if len(problem.Days) == 0 {
+		reply(command, "Every day of the month is already taken", NoParseMode)
+		return nil
+	}
+
+	plan := scheduler.Solve(problem)
+	if apply {
+		return applyPlan(command, plan, names)
+	}
+	return previewPlan(command, plan, names, month)
+}
+
+// Show the plan and the command to apply it
+func previewPlan(command Command, plan scheduler.Plan, names map[int64]string, month time.Time) error {
+	table, err := getPlanTable(plan, names)
+	if err != nil {
+		logger.Log.Error().Err(err).Send()
+		return err
+	}
+	text := fmt.Sprintf("```\n%s\n```\n", table)
+	if plan.Truncated {
+		text += truncatedPlanNote + "\n"
+	}
+	text += fmt.Sprintf("Run `/plan month %s apply` to assign", month.Format("01-2006"))
+	reply(command, text, MarkdownParseMode)
+	return nil
+}
+
+// Assign the whole plan at once, so it is either applied
+// or left out if somebody took a planned day meanwhile
+func applyPlan(command Command, plan scheduler.Plan, names map[int64]string) error {
+	planned := make([]assignment.Assignment, 0, len(plan.Duties))
+	for _, duty := range plan.Duties {
+		startsAt, endsAt := shift.WholeDay(duty.Day)
+		planned = append(planned, assignment.Assignment{
+			ID:        uuid.New(),
+			ChatID:    command.ChatID,
+			At:        duty.Day,
+			StartsAt:  startsAt,
+			EndsAt:    endsAt,
+			UserID:    duty.OperatorID,
+			Operator:  names[duty.OperatorID],
+			Role:      assignment.RolePrimary,
+			CreatedAt: utils.GetToday(),
+		})
+	}
+	change, err := assignment.AssignmentRepo.TakeSlots(
+		context.Background(),
+		planned,
+		getActor(command),
+	)
+	if errors.Is(err, assignment.ErrSlotTaken) {
+		reply(command, "Somebody has just taken a planned day, nothing is assigned. Try /plan again", NoParseMode)
+		return nil
+	}
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to assign the plan", NoParseMode)
+		return err
+	}
+	refreshChatKeyboards(command.ChatID, 0)
+
+	text := fmt.Sprintf("Assigned %d duties", len(plan.Duties))
+	if len(plan.Unfilled) != 0 {
+		text += fmt.Sprintf(", %d days are left for volunteers", len(plan.Unfilled))
+	}
+	if plan.Truncated {
+		text += "\n" + truncatedPlanNote
+	}
+	replyWithUndo(command, text, NoParseMode, change)
+	return nil
+}
@@ -0,0 +1,360 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/recurrence"
+	"github.com/FedoseevAlex/DutyBot/internal/database/shift"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/tasks"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// First argument of /assign making recurrence rule
+const everyArgument = "every"
+
+const recurringUsage = "Usage: /recurring [cancel N | edit N weekday [until DD-MM-YYYY | until never]]"
+
+// Last day recurrence rules are materialized up to
+func getRecurrenceHorizon() time.Time {
+	return utils.GetToday().Add(utils.WeekDuration * time.Duration(viper.GetInt("RecurrenceHorizonWeeks")))
+}
+
+// Parse "wed [until DD-MM-YYYY]". Nil until means forever.
+func parseRecurrence(args []string) (time.Weekday, *time.Time, error) {
+	if len(args) != 1 && (len(args) != 3 || args[1] != "until") {
+		return 0, nil, errors.New("usage: /assign every wed [until DD-MM-YYYY] [backup]")
+	}
+
+	weekdays, err := parseWeekdays(args[0])
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(weekdays) != 1 {
+		return 0, nil, errors.New("recurrence takes a single weekday")
+	}
+
+	if len(args) == 1 || args[2] == "never" {
+		return weekdays[0], nil, nil
+	}
+	until, err := utils.ParseDate(args[2])
+	if err != nil {
+		return 0, nil, err
+	}
+	if until.Before(utils.GetToday()) {
+		return 0, nil, errors.New("recurrence should end in future")
+	}
+	return weekdays[0], &until, nil
+}
+
+func formatRecurrence(rule recurrence.Recurrence) string {
+	text := fmt.Sprintf("%s every %s", rule.Operator, rule.Weekday)
+	if rule.Until != nil {
+		text += " until " + rule.Until.Format(utils.HumanDateFormat)
+	}
+	if rule.Role == assignment.RoleSecondary {
+		text += " as backup"
+	}
+	return text
+}
+
+// Recurrence rules make sense only for chats with whole day duties
+func checkRecurrenceAllowed(chatID int64) error {
+	templates, err := getShiftTemplates(chatID)
+	if err != nil {
+		return err
+	}
+	if len(templates) != 0 || isWeeklyChat(chatID) {
+		return errors.New("only daily duties can recur")
+	}
+	return nil
+}
+
+// /assign every wed [until DD-MM-YYYY] [backup]
+func assignEvery(command Command, args []string, role string) error {
+	if err := checkRecurrenceAllowed(command.ChatID); err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	weekday, until, err := parseRecurrence(args)
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	rule := recurrence.Recurrence{
+		ID:        uuid.New(),
+		ChatID:    command.ChatID,
+		UserID:    command.From.ID,
+		Operator:  command.From.DisplayName(),
+		Weekday:   weekday,
+		Role:      role,
+		Until:     until,
+		CreatedAt: time.Now().UTC(),
+	}
+	err = recurrence.RecurrenceRepo.AddRecurrence(context.Background(), rule)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save recurrence", NoParseMode)
+		return err
+	}
+
+	assigned := materializeRecurrence(rule, getWorkingDays(utils.GetToday(), getRecurrenceHorizon()))
+	refreshChatKeyboards(command.ChatID, command.KeyboardID)
+	reply(
+		command,
+		fmt.Sprintf("%s. Assigned %d days so far", formatRecurrence(rule), assigned),
+		NoParseMode,
+	)
+	return nil
+}
+
+// Operator of rule is not away and nobody holds the role that day
+func isRecurrenceDayFree(ctx context.Context, rule recurrence.Recurrence, date time.Time) (bool, error) {
+	startsAt, endsAt := shift.WholeDay(date)
+	_, absent, err := findAbsence(ctx, rule.UserID, startsAt, endsAt)
+	if err != nil || absent {
+		return false, err
+	}
+
+	for _, role := range []string{rule.Role, otherRole(rule.Role)} {
+		as, err := assignment.AssignmentRepo.GetAssignmentByDate(ctx, date, rule.ChatID, role)
+		if err != nil {
+			return false, err
+		}
+		// Operator can't back up own duty
+		if (role == rule.Role && as.Operator != "") || as.UserID == rule.UserID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Make assignments of rule up to horizon skipping holidays, absences
+// and days taken by others. Every day is handled once, so duties
+// removed afterwards are not assigned again.
+// Returns number of made assignments.
+func materializeRecurrence(rule recurrence.Recurrence, workingDays calendar.TimeSet) int {
+	ctx := context.Background()
+	assigned := 0
+	horizon := getRecurrenceHorizon()
+	for _, date := range rule.PendingDates(utils.GetToday(), horizon) {
+		if _, ok := workingDays[date]; !ok {
+			continue
+		}
+
+		startsAt, endsAt := shift.WholeDay(date)
+		free, err := isRecurrenceDayFree(ctx, rule, date)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+			return assigned
+		}
+		if !free {
+			continue
+		}
+
+		_, err = assignment.AssignmentRepo.AddAssignment(
+			ctx,
+			assignment.Assignment{
+				ID:           uuid.New(),
+				ChatID:       rule.ChatID,
+				At:           date,
+				StartsAt:     startsAt,
+				EndsAt:       endsAt,
+				UserID:       rule.UserID,
+				Operator:     rule.Operator,
+				Role:         rule.Role,
+				RecurrenceID: &rule.ID,
+				CreatedAt:    utils.GetToday(),
+			},
+			assignment.Actor{ID: rule.UserID, Name: rule.Operator},
+		)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+			return assigned
+		}
+		assigned++
+	}
+
+	err := recurrence.RecurrenceRepo.SetMaterializedUntil(ctx, rule.ID, horizon)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+	}
+	return assigned
+}
+
+func materializeRecurrencesTask() {
+	logger.Log.Debug().Msg("Start recurrences materializing")
+	rules, err := recurrence.RecurrenceRepo.GetActiveRecurrences(context.Background(), utils.GetToday())
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Msg("materializeRecurrencesTask job failed to get recurrences")
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	workingDays := getWorkingDays(utils.GetToday(), getRecurrenceHorizon())
+	changed := make(map[int64]bool)
+	// Chats might have switched to shifts or weekly duty since rules were made
+	allowed := make(map[int64]bool)
+	for _, rule := range rules {
+		ok, checked := allowed[rule.ChatID]
+		if !checked {
+			err := checkRecurrenceAllowed(rule.ChatID)
+			ok = err == nil
+			allowed[rule.ChatID] = ok
+			if err != nil {
+				logger.Log.Info().
+					Err(err).
+					Int64("chat_id", rule.ChatID).
+					Msg("Recurrences of chat are skipped")
+			}
+		}
+		if !ok {
+			continue
+		}
+		if materializeRecurrence(rule, workingDays) > 0 {
+			changed[rule.ChatID] = true
+		}
+	}
+	for chatID := range changed {
+		refreshChatKeyboards(chatID, 0)
+	}
+}
+
+func scheduleRecurrenceTask() {
+	_, err := tasks.AddTask(viper.GetString("RecurrenceSchedule"), materializeRecurrencesTask)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Stack().
+			Msg("Unable to schedule task")
+	}
+}
+
+// /recurring lists, edits or cancels recurrence rules of chat
+func manageRecurrences(command Command) error {
+	rules, err := recurrence.RecurrenceRepo.GetRecurrences(context.Background(), command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch recurrences", NoParseMode)
+		return err
+	}
+
+	args := strings.Fields(command.Arguments)
+	if len(args) == 0 {
+		if len(rules) == 0 {
+			reply(command, "No recurring duties. Try /assign every wed", NoParseMode)
+			return nil
+		}
+		lines := make([]string, 0, len(rules))
+		for i, rule := range rules {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, formatRecurrence(rule)))
+		}
+		reply(command, strings.Join(lines, "\n"), NoParseMode)
+		return nil
+	}
+
+	if len(args) < 2 || (args[0] != "cancel" && args[0] != "edit") {
+		reply(command, recurringUsage, NoParseMode)
+		return nil
+	}
+	number, err := strconv.Atoi(args[1])
+	if err != nil || number < 1 || number > len(rules) {
+		reply(command, "No such recurrence, see /recurring", NoParseMode)
+		return nil
+	}
+	rule := rules[number-1]
+
+	if rule.UserID != command.From.ID {
+		isAdmin, err := isChatAdmin(command)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+			reply(command, "Couldn't check your permissions", NoParseMode)
+			return err
+		}
+		if !isAdmin {
+			reply(command, "Only chat admins can change recurrences of others", NoParseMode)
+			return nil
+		}
+	}
+
+	if args[0] == "cancel" {
+		return cancelRecurrence(command, rule)
+	}
+	return editRecurrence(command, rule, args[2:])
+}
+
+// Remove assignments made by rule after today
+func dropRecurrenceAssignments(command Command, rule recurrence.Recurrence) error {
+	_, err := assignment.AssignmentRepo.DeleteRecurrenceAssignments(
+		context.Background(),
+		rule.ID,
+		utils.GetToday().Add(utils.DayDuration),
+		getActor(command),
+	)
+	return err
+}
+
+func cancelRecurrence(command Command, rule recurrence.Recurrence) error {
+	err := dropRecurrenceAssignments(command, rule)
+	if err == nil {
+		err = recurrence.RecurrenceRepo.DeleteRecurrence(context.Background(), rule.ID)
+	}
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to cancel recurrence", NoParseMode)
+		return err
+	}
+	refreshChatKeyboards(command.ChatID, 0)
+
+	reply(command, fmt.Sprintf("Cancelled: %s", formatRecurrence(rule)), NoParseMode)
+	return nil
+}
+
+func editRecurrence(command Command, rule recurrence.Recurrence, args []string) error {
+	if err := checkRecurrenceAllowed(command.ChatID); err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	weekday, until, err := parseRecurrence(args)
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+	rule.Weekday = weekday
+	rule.Until = until
+	// Upcoming duties are dropped, so every day is assigned anew
+	rule.MaterializedUntil = nil
+
+	err = dropRecurrenceAssignments(command, rule)
+	if err == nil {
+		err = recurrence.RecurrenceRepo.UpdateRecurrence(context.Background(), rule)
+	}
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to change recurrence", NoParseMode)
+		return err
+	}
+
+	materializeRecurrence(rule, getWorkingDays(utils.GetToday(), getRecurrenceHorizon()))
+	refreshChatKeyboards(command.ChatID, 0)
+
+	reply(command, fmt.Sprintf("Changed: %s", formatRecurrence(rule)), NoParseMode)
+	return nil
+}
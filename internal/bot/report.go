@@ -0,0 +1,279 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/report"
+	"github.com/FedoseevAlex/DutyBot/internal/tasks"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const reportUsage = "Usage: /report [YYYY-MM], /report monthly on|off or /report to me|chat"
+
+// First day of month before the current one
+func getPreviousMonth() time.Time {
+	year, month, _ := utils.GetToday().Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+}
+
+// CSV report of duties in chat during month starting at first.
+// Empty result means nobody was on duty.
+func buildReport(chatID int64, first time.Time) ([]byte, error) {
+	last := first.AddDate(0, 1, -1)
+	// Weekly duty started in previous month may cover first days
+	assignments, err := assignment.AssignmentRepo.GetAssignmentsInRange(
+		context.Background(),
+		first.Add(-utils.WeekDuration+utils.DayDuration),
+		last,
+		chatID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Holiday counts go to payroll, so they aren't guessed without calendar
+	workingDays, err := calendar.GetCachedWorkingDays(first, last)
+	if err != nil {
+		return nil, err
+	}
+	rows := report.Build(assignments, workingDays, first, last)
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	err = report.WriteCSV(&buf, rows)
+	return buf.Bytes(), err
+}
+
+// Send report of chat to configured admin or the chat itself.
+// Returns ID of chat report was sent to, 0 if there is nothing to report.
+func sendReport(chatID int64, threadID int, first time.Time) (int64, error) {
+	data, err := buildReport(chatID, first)
+	if err != nil || data == nil {
+		return 0, err
+	}
+
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), chatID)
+	if err != nil {
+		return 0, err
+	}
+
+	month := first.Format(statsMonthFmt)
+	doc := tgbot.NewDocument(chatID, tgbot.FileBytes{
+		Name:  fmt.Sprintf("duty-report-%s.csv", month),
+		Bytes: data,
+	})
+	doc.Caption = fmt.Sprintf("Duty report for %s", month)
+	if settings.ReportUserID != 0 {
+		doc.ChatID = settings.ReportUserID
+		doc.Caption += " in " + getChatTitle(chatID)
+		threadID = 0
+	}
+	_, err = sendDocument(doc, threadID)
+	return doc.ChatID, err
+}
+
+// /report [YYYY-MM] sends duty report for the month, previous one by default
+func showReport(command Command) error {
+	args := strings.Fields(command.Arguments)
+	if len(args) != 0 && args[0] == "to" {
+		return setReportRecipient(command, args[1:])
+	}
+	if len(args) != 0 && args[0] == "monthly" {
+		return setMonthlyReport(command, args[1:])
+	}
+	if len(args) > 1 {
+		reply(command, reportUsage, NoParseMode)
+		return nil
+	}
+
+	first := getPreviousMonth()
+	if len(args) == 1 {
+		month, err := time.Parse(statsMonthFmt, args[0])
+		if err != nil {
+			reply(command, reportUsage, NoParseMode)
+			return nil
+		}
+		first = month
+	}
+
+	recipient, err := sendReport(command.ChatID, command.ThreadID, first)
+	switch {
+	case err != nil:
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to send report", NoParseMode)
+		return err
+	case recipient == 0:
+		reply(command, fmt.Sprintf("Nobody was on duty in %s", first.Format(statsMonthFmt)), NoParseMode)
+	case recipient != command.ChatID:
+		reply(command, "Report is sent to the admin in private messages", NoParseMode)
+	default:
+	}
+	return nil
+}
+
+// /report to me|chat chooses where monthly reports go
+func setReportRecipient(command Command, args []string) error {
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can choose where reports go", NoParseMode)
+		return nil
+	}
+
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch chat settings", NoParseMode)
+		return err
+	}
+
+	var text string
+	switch {
+	case len(args) == 1 && args[0] == "me":
+		settings.ReportUserID = command.From.ID
+		text = "Reports will come to you in private messages. Make sure you've started a private chat with me"
+	case len(args) == 1 && args[0] == "chat":
+		settings.ReportUserID = 0
+		text = "Reports will be posted to this chat"
+	default:
+		reply(command, reportUsage, NoParseMode)
+		return nil
+	}
+
+	err = chat.ChatRepo.SaveSettings(context.Background(), settings)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save chat settings", NoParseMode)
+		return err
+	}
+	reply(command, text, NoParseMode)
+	return nil
+}
+
+// /report monthly on|off turns sending of monthly reports on and off
+func setMonthlyReport(command Command, args []string) error {
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can change monthly reports", NoParseMode)
+		return nil
+	}
+
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch chat settings", NoParseMode)
+		return err
+	}
+
+	var text string
+	switch {
+	case len(args) == 1 && args[0] == "on":
+		settings.MonthlyReport = true
+		text = fmt.Sprintf("Report for previous month will be sent on schedule %s", viper.GetString("ReportSchedule"))
+	case len(args) == 1 && args[0] == "off":
+		settings.MonthlyReport = false
+		text = "Monthly reports are off"
+	default:
+		reply(command, reportUsage, NoParseMode)
+		return nil
+	}
+
+	err = chat.ChatRepo.SaveSettings(context.Background(), settings)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save chat settings", NoParseMode)
+		return err
+	}
+	reply(command, text, NoParseMode)
+	return nil
+}
+
+// Bot can't post to the chat anymore, e.g. it was removed from it
+func isBotRemoved(err error) bool {
+	var tgErr *tgbot.Error
+	return errors.As(err, &tgErr) && tgErr.Code == http.StatusForbidden
+}
+
+func monthlyReportTask() {
+	logger.Log.Debug().Msg("Start monthly reports")
+	chats, err := chat.ChatRepo.GetMonthlyReportChats(context.Background())
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Msg("monthlyReportTask job failed to get report chats")
+		return
+	}
+
+	first := getPreviousMonth()
+	for _, chatID := range chats {
+		recipient, err := sendReport(chatID, getAnnounceThreadID(chatID), first)
+		if to, ok := migratedTo(err); ok {
+			err = migrateChat(chatID, to)
+			if err == nil {
+				chatID = to
+				recipient, err = sendReport(chatID, getAnnounceThreadID(chatID), first)
+			}
+		}
+		switch {
+		case err == nil:
+		case isBotRemoved(err) && recipient == chatID:
+			logger.Log.Warn().
+				Err(err).
+				Int64("chat_id", chatID).
+				Msg("Bot is removed from chat, turning monthly reports off")
+			disableMonthlyReport(chatID)
+		default:
+			logger.Log.Error().
+				Err(err).
+				Int64("chat_id", chatID).
+				Msg("monthlyReportTask job failed to send report")
+		}
+	}
+}
+
+func disableMonthlyReport(chatID int64) {
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), chatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return
+	}
+	settings.MonthlyReport = false
+	err = chat.ChatRepo.SaveSettings(context.Background(), settings)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+	}
+}
+
+func scheduleReportTask() {
+	_, err := tasks.AddTask(viper.GetString("ReportSchedule"), monthlyReportTask)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Stack().
+			Msg("Unable to schedule task")
+	}
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Argument choosing secondary operator role
+const backupArgument = "backup"
+
+// Split role out of command arguments.
+// Returns remaining arguments and the role, primary by default.
+func cutRole(arguments string) (string, string) {
+	role := assignment.RolePrimary
+	rest := make([]string, 0)
+	for _, field := range strings.Fields(arguments) {
+		switch strings.ToLower(field) {
+		case backupArgument, assignment.RoleSecondary:
+			role = assignment.RoleSecondary
+		default:
+			rest = append(rest, field)
+		}
+	}
+	return strings.Join(rest, " "), role
+}
+
+func otherRole(role string) string {
+	if role == assignment.RoleSecondary {
+		return assignment.RolePrimary
+	}
+	return assignment.RoleSecondary
+}
+
+// Note for messages about backup assignments
+func roleSuffix(as assignment.Assignment) string {
+	if as.IsBackup() {
+		return " as backup"
+	}
+	return ""
+}
+
+// Arguments of /assign and /reset for the same slot and role
+func getSlotArguments(as assignment.Assignment) string {
+	arguments := []string{as.At.Format(utils.AssignDateFormat)}
+	if start := shiftStart(as); start != "" {
+		arguments = append(arguments, start)
+	}
+	if as.IsBackup() {
+		arguments = append(arguments, backupArgument)
+	}
+	return strings.Join(arguments, " ")
+}
+
+// Backup of primary assignment for announcements, empty if there is none
+func backupNote(ctx context.Context, as assignment.Assignment) string {
+	backup := mentionBackup(ctx, as.ChatID, as.StartsAt)
+	if backup == "" {
+		return ""
+	}
+	return ", backup is " + backup
+}
+
+// Mention of secondary operator on duty at moment
+// or empty string if there is none
+func mentionBackup(ctx context.Context, chatID int64, moment time.Time) string {
+	backup, err := assignment.AssignmentRepo.GetAssignmentAt(ctx, moment, chatID, assignment.RoleSecondary)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return ""
+	}
+	if backup.Operator == "" {
+		return ""
+	}
+	return mentionOperator(ctx, backup)
+}
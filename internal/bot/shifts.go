@@ -0,0 +1,342 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/checkpoint"
+	"github.com/FedoseevAlex/DutyBot/internal/database/shift"
+	"github.com/FedoseevAlex/DutyBot/internal/events"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/tasks"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const shiftTimeFormat = "15:04"
+
+// Runs of announceShiftsTask don't overlap
+var shiftCheckLock sync.Mutex
+
+func getShiftTemplates(chatID int64) ([]shift.Template, error) {
+	return shift.ShiftRepo.GetTemplates(context.Background(), chatID)
+}
+
+func formatShifts(templates []shift.Template) string {
+	shifts := make([]string, 0, len(templates))
+	for _, t := range templates {
+		shifts = append(shifts, t.String())
+	}
+	return strings.Join(shifts, ", ")
+}
+
+// Command arguments lack shift start in chat with shifts
+type noShiftError struct {
+	templates []shift.Template
+}
+
+func (e noShiftError) Error() string {
+	return fmt.Sprintf(
+		"this chat has shifts %s. Add shift start after date, e.g. %s",
+		formatShifts(e.templates),
+		e.templates[0].String()[:len(shiftTimeFormat)],
+	)
+}
+
+// Find duty bounds for day. Chats with shift templates
+// need shift start (HH:MM) among command arguments.
+// Weekly chats get whole week starting at day.
+func getDutyBounds(chatID int64, day time.Time, arguments string) (time.Time, time.Time, error) {
+	if isWeeklyChat(chatID) {
+		start := utils.GetStartOfWeek(day)
+		return start, start.Add(utils.WeekDuration), nil
+	}
+
+	templates, err := getShiftTemplates(chatID)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	clock := ""
+	for _, field := range strings.Fields(arguments) {
+		if strings.Contains(field, ":") {
+			clock = field
+		}
+	}
+
+	switch {
+	case len(templates) == 0 && clock == "":
+		start, end := shift.WholeDay(day)
+		return start, end, nil
+	case len(templates) == 0:
+		return time.Time{}, time.Time{}, errors.New("this chat has no shifts, duty takes whole day")
+	case clock == "":
+		return time.Time{}, time.Time{}, noShiftError{templates: templates}
+	}
+
+	minute, err := shift.ParseClock(clock)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	t, ok := shift.FindByStart(templates, minute)
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("no shift starts at %s, try one of %s", clock, formatShifts(templates))
+	}
+	start, end := t.Bounds(day)
+	return start, end, nil
+}
+
+// Human readable duty time, with hours for shifts
+func formatDutyTime(as assignment.Assignment) string {
+	if as.IsWeek() {
+		return formatWeek(as.At)
+	}
+	if !as.IsShift() {
+		return as.At.Format(utils.HumanDateFormat)
+	}
+	return fmt.Sprintf(
+		"%s %s-%s",
+		as.At.Format(utils.HumanDateFormat),
+		as.StartsAt.Format(shiftTimeFormat),
+		as.EndsAt.Format(shiftTimeFormat),
+	)
+}
+
+// Shift start suitable for command arguments, empty for whole day duties
+func shiftStart(as assignment.Assignment) string {
+	if !as.IsShift() {
+		return ""
+	}
+	return as.StartsAt.Format(shiftTimeFormat)
+}
+
+// Shift start of template at position suitable for command arguments,
+// empty if chat has no such shift
+func getShiftStart(chatID int64, position int) string {
+	templates, err := getShiftTemplates(chatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return ""
+	}
+	for _, t := range templates {
+		if t.Position == position {
+			return t.String()[:len(shiftTimeFormat)]
+		}
+	}
+	return ""
+}
+
+// Assignment of duty given in arguments.
+// Chats with shifts need shift start among arguments.
+func getAssignmentToReset(chatID int64, day time.Time, arguments, role string) (assignment.Assignment, error) {
+	startsAt, _, err := getDutyBounds(chatID, day, arguments)
+	if err != nil {
+		return assignment.Assignment{}, err
+	}
+	return assignment.AssignmentRepo.GetAssignmentAt(context.Background(), startsAt, chatID, role)
+}
+
+// Ask which shift of day action is meant for.
+// Buttons show operators of taken shifts.
+func askShift(command Command, action callback.Action, day time.Time, templates []shift.Template) error {
+	rows := make([][]tgbot.InlineKeyboardButton, 0, len(templates))
+	for _, t := range templates {
+		label := t.String()
+		start, _ := t.Bounds(day)
+		as, err := assignment.AssignmentRepo.GetAssignmentAt(
+			context.Background(),
+			start,
+			command.ChatID,
+			assignment.RolePrimary,
+		)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+			return err
+		}
+		if as.Operator != "" {
+			label += " " + as.Operator
+		}
+		rows = append(rows, tgbot.NewInlineKeyboardRow(callbackButton(label, callback.Data{
+			Action: action,
+			View:   callback.ViewShift,
+			Date:   day,
+			Shift:  t.Position,
+		})))
+	}
+
+	msg := tgbot.NewMessage(command.ChatID, fmt.Sprintf("Which shift of %s?", day.Format(utils.HumanDateFormat)))
+	msg.ReplyMarkup = tgbot.NewInlineKeyboardMarkup(rows...)
+	_, err := send(msg, command.ThreadID)
+	return err
+}
+
+// Handle shift chosen with askShift buttons.
+// Buttons are removed once shift is chosen.
+func processShiftCallback(command Command) error {
+	var err error
+	switch command.Callback.Action {
+	case callback.ActionAssign:
+		err = assign(command)
+	case callback.ActionReset:
+		err = resetAssign(command)
+	default:
+		return fmt.Errorf("unknown shift callback %d", command.Callback.Action)
+	}
+	editKeyboard(command.ChatID, command.KeyboardID, tgbot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbot.InlineKeyboardButton{},
+	})
+	return err
+}
+
+func manageShifts(command Command) error {
+	args := strings.Fields(command.Arguments)
+	if len(args) == 0 {
+		return listShifts(command)
+	}
+
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can change shifts", NoParseMode)
+		return nil
+	}
+
+	var templates []shift.Template
+	switch args[0] {
+	case "set":
+		if isWeeklyChat(command.ChatID) {
+			reply(command, "Weekly duty can't be split into shifts, try /period day first", NoParseMode)
+			return nil
+		}
+		templates, err = parseShifts(args[1:])
+		if err != nil {
+			reply(command, err.Error(), NoParseMode)
+			return err
+		}
+	case "reset":
+	default:
+		reply(command, "Usage: /shifts [set HH:MM-HH:MM ... | reset]", NoParseMode)
+		return nil
+	}
+
+	err = shift.ShiftRepo.SetTemplates(context.Background(), command.ChatID, templates)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save shifts", NoParseMode)
+		return err
+	}
+	return listShifts(command)
+}
+
+func parseShifts(args []string) ([]shift.Template, error) {
+	if len(args) == 0 {
+		return nil, errors.New("usage: /shifts set HH:MM-HH:MM ...")
+	}
+
+	templates := make([]shift.Template, 0, len(args))
+	for _, arg := range args {
+		t, err := shift.ParseTemplate(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range templates {
+			if t.Overlaps(other) {
+				return nil, fmt.Errorf("shifts %s and %s overlap", other, t)
+			}
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+func listShifts(command Command) error {
+	templates, err := getShiftTemplates(command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch shifts", NoParseMode)
+		return err
+	}
+	if len(templates) == 0 {
+		reply(command, "No shifts, duty takes whole day", NoParseMode)
+		return nil
+	}
+	reply(command, fmt.Sprintf("Shifts: %s", formatShifts(templates)), NoParseMode)
+	return nil
+}
+
+// Announce operators of shifts started since previous run.
+// Progress is saved, so shifts started while bot was down
+// are announced unless they are already over.
+func announceShiftsTask() {
+	shiftCheckLock.Lock()
+	defer shiftCheckLock.Unlock()
+
+	ctx := context.Background()
+	now := utils.GetNow()
+	checkedAt, err := checkpoint.CheckpointRepo.GetCheckpoint(ctx, checkpoint.ShiftAnnounce)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Msg("announceShiftsTask job failed to get checkpoint")
+		return
+	}
+	if checkedAt.IsZero() {
+		// Nothing is announced retroactively on the first run
+		checkedAt = now
+	}
+
+	shifts, err := assignment.AssignmentRepo.GetShiftsStartingBetween(ctx, checkedAt, now)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Msg("announceShiftsTask job failed to get shifts")
+		return
+	}
+	// Saved before announcing so failure doesn't repeat announcements
+	err = checkpoint.CheckpointRepo.SetCheckpoint(ctx, checkpoint.ShiftAnnounce, now)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Msg("announceShiftsTask job failed to save checkpoint")
+		return
+	}
+
+	for _, as := range shifts {
+		if as.IsBackup() || !as.EndsAt.After(now) {
+			continue
+		}
+		announce(
+			as.ChatID,
+			fmt.Sprintf(
+				"%s is on duty until %s%s",
+				mentionOperator(ctx, as),
+				as.EndsAt.Format(shiftTimeFormat),
+				backupNote(ctx, as),
+			),
+			HTMLParseMode,
+		)
+		events.Publish(events.NewEvent(events.DutyStarted, as.ChatID, as))
+	}
+}
+
+func scheduleShiftsTask() {
+	_, err := tasks.AddTask(viper.GetString("ShiftAnnounceSchedule"), announceShiftsTask)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Stack().
+			Msg("Unable to schedule task")
+	}
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/stats"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const (
+	monthsInQuarter = 3
+	statsWeekdays   = "Mo Tu We Th Fr"
+	statsMonthFmt   = "2006-01"
+	statsYearFmt    = "2006"
+)
+
+// Parse period for statistics. Supported values are
+// month (default), quarter, year, YYYY-MM and YYYY.
+// Returns first and last day of period.
+func parseStatsPeriod(period string) (time.Time, time.Time, error) {
+	today := utils.GetToday()
+	year, month, _ := today.Date()
+
+	switch period {
+	case "", "month":
+		from := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 1, -1), nil
+	case "quarter":
+		firstMonth := month - (month-1)%monthsInQuarter
+		from := time.Date(year, firstMonth, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, monthsInQuarter, -1), nil
+	case "year":
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, -1), nil
+	}
+
+	if from, err := time.Parse(statsMonthFmt, period); err == nil {
+		return from, from.AddDate(0, 1, -1), nil
+	}
+	if from, err := time.Parse(statsYearFmt, period); err == nil {
+		return from, from.AddDate(1, 0, -1), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf(
+		"'%s' is not a period. Try month, quarter, year, YYYY-MM or YYYY",
+		period,
+	)
+}
+
+func formatFairness(s stats.OperatorStats) string {
+	mark := ""
+	switch {
+	case s.IsOver():
+		mark = " over"
+	case s.IsUnder():
+		mark = " under"
+	}
+	return fmt.Sprintf("%+.1f%s", s.Fairness, mark)
+}
+
+func getStatsTable(chatID int64, from, to time.Time) (string, error) {
+	assignments, err := assignment.AssignmentRepo.GetAssignmentsInRange(
+		context.Background(),
+		from,
+		to,
+		chatID,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	resets, err := assignment.AssignmentRepo.GetAssignmentEvents(
+		context.Background(),
+		assignment.EventFilter{
+			ChatID: chatID,
+			From:   from,
+			To:     to,
+			Kind:   assignment.EventDelete,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	if len(assignments) == 0 && len(resets) == 0 {
+		return "", nil
+	}
+
+	workingDays, err := calendar.GetWorkingDays(
+		from.Add(-utils.DayDuration),
+		to.Add(utils.DayDuration),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	table := utils.NewPrettyTable()
+	table.AddRow([]string{"operator", "duties", statsWeekdays, "near off", "resets", "fair"})
+	for _, s := range stats.Compute(assignments, resets, workingDays) {
+		weekdays := make([]string, 0, len(s.Weekdays))
+		for day := time.Monday; day <= time.Friday; day++ {
+			weekdays = append(weekdays, fmt.Sprintf("%-2d", s.Weekdays[day]))
+		}
+		table.AddRow([]string{
+			s.Operator,
+			strings.TrimSuffix(fmt.Sprintf("%.1f", s.Duties), ".0"),
+			strings.Join(weekdays, " "),
+			strconv.Itoa(s.HolidayAdjacent),
+			strconv.Itoa(s.Resets),
+			formatFairness(s),
+		})
+	}
+	return table.String()
+}
+
+func showStats(command Command) error {
+	from, to, err := parseStatsPeriod(strings.TrimSpace(command.Arguments))
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	table, err := getStatsTable(command.ChatID, from, to)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't compute statistics", NoParseMode)
+		return err
+	}
+	if table == "" {
+		table = "Nothing to show"
+	}
+
+	reply(
+		command,
+		fmt.Sprintf(
+			"```\n%s - %s\n%s\n```",
+			from.Format(utils.AssignDateFormat),
+			to.Format(utils.AssignDateFormat),
+			table,
+		),
+		MarkdownParseMode,
+	)
+	return nil
+}
@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/pending"
+	"github.com/FedoseevAlex/DutyBot/internal/database/user"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Operators swap own duty only, with consent of the other one
+func askSwap(command Command, swapped [2]assignment.Assignment) error {
+	if swapped[1].UserID == command.From.ID {
+		swapped[0], swapped[1] = swapped[1], swapped[0]
+	}
+	if swapped[0].UserID != command.From.ID {
+		reply(command, "Only chat admins can swap duties of others", NoParseMode)
+		return nil
+	}
+	err := requestSwap(context.Background(), command.From, swapped[0], swapped[1])
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to ask for swap", NoParseMode)
+		return err
+	}
+	reply(command, fmt.Sprintf("Asked %s to swap, waiting for the answer", swapped[1].Operator), NoParseMode)
+	return nil
+}
+
+// Ask operator of other duty to take mine in exchange.
+// Request goes to private chat, or to the duty chat
+// if the operator hasn't started a private chat with bot.
+func requestSwap(ctx context.Context, from user.User, mine, other assignment.Assignment) error {
+	request, err := pending.NewPending(
+		mine.ChatID,
+		from.ID,
+		pending.KindSwap,
+		[]uuid.UUID{mine.ID, other.ID},
+		viper.GetDuration("SwapRequestTimeout"),
+	)
+	if err != nil {
+		return err
+	}
+	err = pending.PendingRepo.AddPending(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf(
+		"%s asks to swap duties in %s: you take %s, they take %s",
+		html.EscapeString(from.DisplayName()),
+		html.EscapeString(getChatTitle(mine.ChatID)),
+		html.EscapeString(formatDutyTime(mine)),
+		html.EscapeString(formatDutyTime(other)),
+	)
+	keyboard := tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(
+		callbackButton("Accept", callback.Data{Action: callback.ActionConfirm, View: callback.ViewDashboard, ID: request.ID}),
+		callbackButton("Decline", callback.Data{Action: callback.ActionCancel, View: callback.ViewDashboard, ID: request.ID}),
+	))
+
+	msg := tgbot.NewMessage(other.UserID, text)
+	msg.ParseMode = HTMLParseMode
+	msg.ReplyMarkup = keyboard
+	_, err = bot.Send(msg)
+	if err == nil {
+		return nil
+	}
+
+	msg = tgbot.NewMessage(mine.ChatID, mentionOperator(ctx, other)+", "+text)
+	msg.ParseMode = HTMLParseMode
+	msg.ReplyMarkup = keyboard
+	_, err = send(msg, getAnnounceThreadID(mine.ChatID))
+	return err
+}
+
+// Get swap request of callback if it is addressed to the user who pressed the button.
+// Returns duty of requester and duty of the user.
+func getSwapRequest(command Command) (pending.Pending, [2]assignment.Assignment, error) {
+	var swapped [2]assignment.Assignment
+	request, err := pending.PendingRepo.GetPending(context.Background(), command.Callback.ID)
+	if err != nil {
+		return request, swapped, err
+	}
+	ids, err := request.AssignmentIDs()
+	if err != nil {
+		return request, swapped, err
+	}
+	if request.Kind != pending.KindSwap || len(ids) != len(swapped) {
+		return request, swapped, pending.ErrNotFound
+	}
+
+	for i, id := range ids {
+		swapped[i], err = assignment.AssignmentRepo.GetAssignment(context.Background(), id)
+		if err != nil {
+			return request, swapped, err
+		}
+	}
+	// Duties might have changed hands since request was made
+	if swapped[0].UserID != request.UserID || swapped[0].At.Before(utils.GetToday()) {
+		return request, swapped, assignment.ErrNotFound
+	}
+	return request, swapped, nil
+}
+
+// Absence might be reported after request was made.
+// Drops the request and tells about absence if the swap is no longer possible.
+func rejectAbsentSwap(
+	ctx context.Context,
+	command Command,
+	request pending.Pending,
+	swapped [2]assignment.Assignment,
+) (bool, error) {
+	absent, err := findSwapAbsence(ctx, swapped[0], swapped[1])
+	if err != nil || absent == "" {
+		return false, err
+	}
+	err = pending.PendingRepo.DeletePending(ctx, request.ID)
+	if err != nil {
+		return false, err
+	}
+	editCallbackMessage(command, "Can't swap: "+absent, uuid.Nil)
+	return true, nil
+}
+
+func acceptSwap(command Command) error {
+	ctx := context.Background()
+	request, swapped, err := getSwapRequest(command)
+	switch {
+	case errors.Is(err, pending.ErrNotFound) || errors.Is(err, assignment.ErrNotFound):
+		editCallbackMessage(command, "This swap request is over", uuid.Nil)
+		return nil
+	case err != nil:
+		return err
+	case swapped[1].UserID != command.From.ID:
+		return nil
+	default:
+	}
+
+	rejected, err := rejectAbsentSwap(ctx, command, request, swapped)
+	if err != nil || rejected {
+		return err
+	}
+
+	_, err = pending.PendingRepo.TakePending(ctx, request.ID)
+	switch {
+	case errors.Is(err, pending.ErrNotFound):
+		// Pressed twice
+		return nil
+	case err != nil:
+		return err
+	default:
+	}
+
+	change, err := assignment.AssignmentRepo.SwapAssignments(
+		ctx,
+		swapped[0].ID,
+		swapped[1].ID,
+		getActor(command),
+	)
+	if err != nil {
+		return err
+	}
+	refreshChatKeyboards(request.ChatID, 0)
+
+	editCallbackMessage(command, "Swapped", uuid.Nil)
+	announceWithUndo(
+		request.ChatID,
+		fmt.Sprintf(
+			"%s is on duty %s, %s is on duty %s",
+			mentionOperator(ctx, swapped[1]),
+			html.EscapeString(formatDutyTime(swapped[0])),
+			mentionOperator(ctx, swapped[0]),
+			html.EscapeString(formatDutyTime(swapped[1])),
+		),
+		HTMLParseMode,
+		change,
+	)
+	return nil
+}
+
+func declineSwap(command Command) error {
+	request, swapped, err := getSwapRequest(command)
+	switch {
+	case errors.Is(err, pending.ErrNotFound) || errors.Is(err, assignment.ErrNotFound):
+		editCallbackMessage(command, "This swap request is over", uuid.Nil)
+		return nil
+	case err != nil:
+		return err
+	case swapped[1].UserID != command.From.ID:
+		return nil
+	default:
+	}
+
+	err = pending.PendingRepo.DeletePending(context.Background(), request.ID)
+	if err != nil {
+		return err
+	}
+	editCallbackMessage(command, "Swap is declined", uuid.Nil)
+
+	_, err = bot.Send(tgbot.NewMessage(
+		request.UserID,
+		fmt.Sprintf(
+			"%s declined to swap %s for %s",
+			command.From.DisplayName(),
+			formatDutyTime(swapped[1]),
+			formatDutyTime(swapped[0]),
+		),
+	))
+	if err != nil {
+		logger.Log.Warn().Err(err).Int64("user_id", request.UserID).Msg("Unable to notify swap requester")
+	}
+	return nil
+}
@@ -5,12 +5,13 @@ import (
 	"fmt"
 
 	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/events"
 	"github.com/FedoseevAlex/DutyBot/internal/logger"
 	"github.com/FedoseevAlex/DutyBot/internal/utils"
 )
 
 func announceDutyTask() {
-	msgFormat := "@%s is on duty today"
+	msgFormat := "%s is on duty today"
 	logger.Log.Debug().Msg("Start duty announcing")
 	assignments, err := assignment.AssignmentRepo.GetAssignmentScheduleAllChats(
 		context.Background(),
@@ -24,13 +25,21 @@ func announceDutyTask() {
 	}
 
 	for _, assignment := range assignments {
+		if assignment.IsShift() || assignment.IsWeek() || assignment.IsBackup() {
+			// Shifts are announced when they start
+			// and weeks on their first working day
+			continue
+		}
 		logger.Log.Debug().Msgf("Sending %+v\n", assignment)
-		sendMessage(
+		announce(
 			assignment.ChatID,
-			fmt.Sprintf(msgFormat, assignment.Operator),
-			NoParseMode,
+			fmt.Sprintf(msgFormat, mentionOperator(context.Background(), assignment))+
+				backupNote(context.Background(), assignment),
+			HTMLParseMode,
 		)
+		events.Publish(events.NewEvent(events.DutyStarted, assignment.ChatID, assignment))
 	}
+	announceWeekDuties()
 }
 
 func warnAboutFreeSlots() {
@@ -57,7 +66,7 @@ func warnAboutFreeSlots() {
 			continue
 		}
 
-		sendMessage(
+		announce(
 			chatID,
 			fmt.Sprintf("Free slots still available!\n%s\n", outputSlots),
 			NoParseMode,
@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+)
+
+// Forum topic fields of telegram message.
+// tgbot library doesn't know about forums yet.
+type topicFields struct {
+	MessageThreadID int  `json:"message_thread_id"`
+	IsTopicMessage  bool `json:"is_topic_message"`
+}
+
+type updateTopics struct {
+	Message       *topicFields `json:"message"`
+	EditedMessage *topicFields `json:"edited_message"`
+	CallbackQuery *struct {
+		Message *topicFields `json:"message"`
+	} `json:"callback_query"`
+}
+
+// Get forum topic of update from raw telegram json.
+// Returns 0 for messages outside of topics.
+func getThreadID(rawUpdate []byte) int {
+	var topics updateTopics
+	if err := json.Unmarshal(rawUpdate, &topics); err != nil {
+		return 0
+	}
+
+	var msg *topicFields
+	switch {
+	case topics.Message != nil:
+		msg = topics.Message
+	case topics.EditedMessage != nil:
+		msg = topics.EditedMessage
+	case topics.CallbackQuery != nil:
+		msg = topics.CallbackQuery.Message
+	}
+	if msg == nil || !msg.IsTopicMessage {
+		return 0
+	}
+	return msg.MessageThreadID
+}
+
+// Send message to forum topic. Zero threadID means General topic.
+func send(msg tgbot.MessageConfig, threadID int) (tgbot.Message, error) {
+	if threadID == 0 {
+		return bot.Send(msg)
+	}
+
+	params := make(tgbot.Params)
+	params.AddNonZero64("chat_id", msg.ChatID)
+	params.AddNonZero("message_thread_id", threadID)
+	params.AddNonEmpty("text", msg.Text)
+	params.AddNonEmpty("parse_mode", msg.ParseMode)
+	params.AddBool("disable_web_page_preview", msg.DisableWebPagePreview)
+	params.AddNonZero("reply_to_message_id", msg.ReplyToMessageID)
+	params.AddBool("disable_notification", msg.DisableNotification)
+	if err := params.AddInterface("reply_markup", msg.ReplyMarkup); err != nil {
+		return tgbot.Message{}, err
+	}
+
+	resp, err := bot.MakeRequest("sendMessage", params)
+	if err != nil {
+		return tgbot.Message{}, err
+	}
+
+	var message tgbot.Message
+	err = json.Unmarshal(resp.Result, &message)
+	return message, err
+}
+
+// Send document to forum topic. Zero threadID means General topic.
+func sendDocument(doc tgbot.DocumentConfig, threadID int) (tgbot.Message, error) {
+	if threadID == 0 {
+		return bot.Send(doc)
+	}
+
+	params := make(tgbot.Params)
+	params.AddNonZero64("chat_id", doc.ChatID)
+	params.AddNonZero("message_thread_id", threadID)
+	params.AddNonEmpty("caption", doc.Caption)
+	params.AddNonEmpty("parse_mode", doc.ParseMode)
+
+	resp, err := bot.UploadFiles(
+		"sendDocument",
+		params,
+		[]tgbot.RequestFile{{Name: "document", Data: doc.File}},
+	)
+	if err != nil {
+		return tgbot.Message{}, err
+	}
+
+	var message tgbot.Message
+	err = json.Unmarshal(resp.Result, &message)
+	return message, err
+}
+
+// Receive updates along with raw json to find out forum topics
+func getUpdates(offset int, timeout int) ([]json.RawMessage, error) {
+	params := make(tgbot.Params)
+	params.AddNonZero("offset", offset)
+	params.AddNonZero("timeout", timeout)
+
+	resp, err := bot.MakeRequest("getUpdates", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []json.RawMessage
+	err = json.Unmarshal(resp.Result, &updates)
+	return updates, err
+}
+
+// Topic where announcements for chat should be posted
+func getAnnounceThreadID(chatID int64) int {
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), chatID)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Int64("chat_id", chatID).
+			Msg("Unable to get chat settings")
+		return 0
+	}
+	return settings.AnnounceThreadID
+}
+
+// Choose forum topic for announcements and free slot warnings
+func setAnnounceTopic(command Command) error {
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can choose announcements topic", NoParseMode)
+		return nil
+	}
+
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch chat settings", NoParseMode)
+		return err
+	}
+
+	settings.AnnounceThreadID = command.ThreadID
+	if strings.TrimSpace(command.Arguments) == "reset" {
+		settings.AnnounceThreadID = 0
+	}
+
+	err = chat.ChatRepo.SaveSettings(context.Background(), settings)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save chat settings", NoParseMode)
+		return err
+	}
+
+	if settings.AnnounceThreadID == 0 {
+		reply(command, "Announcements will be posted to General topic", NoParseMode)
+		return nil
+	}
+	reply(command, "Announcements will be posted to this topic", NoParseMode)
+	return nil
+}
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+)
+
+func undoKeyboard(change uuid.UUID) tgbot.InlineKeyboardMarkup {
+	return tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(
+		callbackButton("Undo", callback.Data{Action: callback.ActionUndo, View: callback.ViewUndo, ID: change}),
+	))
+}
+
+// Send message with button reverting schedule change.
+// Falls back to plain message if there is nothing to undo.
+func sendWithUndo(chatID int64, threadID int, message string, parseMode string, change uuid.UUID) {
+	if change == uuid.Nil {
+		sendMessage(chatID, threadID, message, parseMode)
+		return
+	}
+
+	msg := tgbot.NewMessage(chatID, message)
+	if parseMode != NoParseMode {
+		msg.ParseMode = parseMode
+	}
+	msg.ReplyMarkup = undoKeyboard(change)
+	_, err := send(msg, threadID)
+	if err != nil {
+		logger.Log.Error().Err(err).Send()
+	}
+}
+
+func replyWithUndo(command Command, message string, parseMode string, change uuid.UUID) {
+	sendWithUndo(command.ChatID, command.ThreadID, message, parseMode, change)
+}
+
+func announceWithUndo(chatID int64, message string, parseMode string, change uuid.UUID) {
+	sendWithUndo(chatID, getAnnounceThreadID(chatID), message, parseMode, change)
+}
+
+func removeUndoButton(command Command) {
+	editKeyboard(
+		command.ChatID,
+		command.KeyboardID,
+		tgbot.InlineKeyboardMarkup{InlineKeyboard: [][]tgbot.InlineKeyboardButton{}},
+	)
+}
+
+// Revert change the pressed Undo button was sent for.
+// Only author of the change or chat admins can undo it.
+func undoChange(command Command) error {
+	if time.Since(command.Callback.IssuedAt) > viper.GetDuration("UndoTimeout") {
+		removeUndoButton(command)
+		reply(command, "Too late to undo, change the schedule again instead", NoParseMode)
+		return nil
+	}
+
+	ctx := context.Background()
+	changes, err := assignment.AssignmentRepo.GetAssignmentEvents(
+		ctx,
+		assignment.EventFilter{ChatID: command.ChatID, ChangeID: command.Callback.ID},
+	)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if !changes[0].MadeBy(command.From.ID) {
+		isAdmin, err := isChatAdmin(command)
+		if err != nil {
+			return err
+		}
+		if !isAdmin {
+			reply(command, "Only the author of the change or chat admins can undo it", NoParseMode)
+			return nil
+		}
+	}
+
+	_, err = assignment.AssignmentRepo.UndoChange(ctx, command.Callback.ID, getActor(command))
+	switch {
+	case errors.Is(err, assignment.ErrNotUndone):
+		removeUndoButton(command)
+		reply(command, err.Error(), NoParseMode)
+		return nil
+	case err != nil:
+		return err
+	default:
+	}
+	refreshChatKeyboards(command.ChatID, 0)
+	removeUndoButton(command)
+
+	reply(command, "Undone", NoParseMode)
+	return nil
+}
+
+// /confirmreset [on|off] makes resets of other people's duties ask for confirmation
+func setConfirmReset(command Command) error {
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch chat settings", NoParseMode)
+		return err
+	}
+
+	switch strings.TrimSpace(command.Arguments) {
+	case "":
+		if settings.ConfirmReset {
+			reply(command, "Resets of other people's duties ask for confirmation", NoParseMode)
+		} else {
+			reply(command, "Resets happen without confirmation, Undo button is there for mistakes", NoParseMode)
+		}
+		return nil
+	case "on":
+		settings.ConfirmReset = true
+	case "off":
+		settings.ConfirmReset = false
+	default:
+		reply(command, "Usage: /confirmreset [on|off]", NoParseMode)
+		return nil
+	}
+
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can change reset confirmation", NoParseMode)
+		return nil
+	}
+
+	err = chat.ChatRepo.SaveSettings(context.Background(), settings)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save chat settings", NoParseMode)
+		return err
+	}
+	reply(command, fmt.Sprintf("Reset confirmation is %s", strings.TrimSpace(command.Arguments)), NoParseMode)
+	return nil
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/user"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+)
+
+func userFromTelegram(from *tgbot.User) user.User {
+	if from == nil {
+		return user.User{}
+	}
+	return user.User{
+		ID:        from.ID,
+		Username:  from.UserName,
+		FirstName: from.FirstName,
+		LastName:  from.LastName,
+		UpdatedAt: time.Now().UTC(),
+	}
+}
+
+// Users whose names are known to be saved already
+var seenUsers = struct {
+	sync.Mutex
+	users map[int64]user.User
+}{users: make(map[int64]user.User)}
+
+// Telegram names of user differ from saved ones
+func isUserChanged(u user.User) bool {
+	seenUsers.Lock()
+	seen, ok := seenUsers.users[u.ID]
+	seenUsers.Unlock()
+	if !ok {
+		saved, err := user.UserRepo.GetUser(context.Background(), u.ID)
+		if err != nil {
+			return true
+		}
+		seen = saved
+	}
+
+	if !u.HasSameNames(seen) {
+		return true
+	}
+	seenUsers.Lock()
+	seenUsers.users[u.ID] = seen
+	seenUsers.Unlock()
+	return false
+}
+
+// Keep users table up to date with telegram
+// so renamed users don't lose their assignments.
+// Database is written only when user names change.
+func rememberUser(u user.User) {
+	if u.ID == 0 || !isUserChanged(u) {
+		return
+	}
+	err := user.UserRepo.UpsertUser(context.Background(), u)
+	if err != nil {
+		logger.Log.Error().
+			Stack().
+			Err(err).
+			Int64("user_id", u.ID).
+			Msg("Unable to save user")
+		return
+	}
+	seenUsers.Lock()
+	seenUsers.users[u.ID] = u
+	seenUsers.Unlock()
+}
+
+// Mention assignee of assignment.
+// Result should be sent with HTMLParseMode.
+func mentionOperator(ctx context.Context, as assignment.Assignment) string {
+	u, err := user.UserRepo.GetUser(ctx, as.UserID)
+	if err != nil {
+		logger.Log.Warn().
+			Err(err).
+			Int64("user_id", as.UserID).
+			Msg("Unable to get assignee")
+		// Link by ID works without username
+		return user.User{ID: as.UserID, FirstName: as.Operator}.Mention()
+	}
+	return u.Mention()
+}
+
+// Author of schedule change made by command
+func getActor(command Command) assignment.Actor {
+	return assignment.Actor{ID: command.From.ID, Name: command.From.DisplayName()}
+}
@@ -0,0 +1,292 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/watch"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const watchUsage = "Usage: /watch [DD-MM-YYYY | cancel DD-MM-YYYY]"
+
+// /watch [DD-MM-YYYY | cancel DD-MM-YYYY] waits for taken day to free up
+func watchSlot(command Command) error {
+	args := strings.Fields(command.Arguments)
+	switch {
+	case len(args) == 0:
+		return listWatches(command)
+	case args[0] == "cancel" && len(args) == 2:
+		return cancelWatch(command, args[1])
+	case len(args) != 1:
+		reply(command, watchUsage, NoParseMode)
+		return nil
+	}
+
+	return addWatch(command, args[0])
+}
+
+// Watch taken day unless it is free or taken by the user already
+func addWatch(command Command, possibleDate string) error {
+	templates, err := getShiftTemplates(command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return err
+	}
+	if len(templates) != 0 {
+		reply(command, "Shifts can't be watched yet, check /freeslots", NoParseMode)
+		return nil
+	}
+
+	dutydate, err := parseDutyDate(command.ChatID, possibleDate)
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	as, err := assignment.AssignmentRepo.GetAssignmentByDate(
+		context.Background(),
+		dutydate,
+		command.ChatID,
+		assignment.RolePrimary,
+	)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return err
+	}
+	date := dutydate.Format(utils.AssignDateFormat)
+	switch {
+	case as.Operator == "":
+		reply(command, fmt.Sprintf("%s is free, try /assign %s", date, date), NoParseMode)
+		return nil
+	case as.UserID == command.From.ID:
+		reply(command, "You are already on duty then", NoParseMode)
+		return nil
+	}
+
+	err = watch.WatchRepo.AddWatch(context.Background(), watch.Watch{
+		ID:        uuid.New(),
+		ChatID:    command.ChatID,
+		UserID:    command.From.ID,
+		At:        dutydate,
+		CreatedAt: utils.GetNow(),
+	})
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save watch", NoParseMode)
+		return err
+	}
+	reply(
+		command,
+		fmt.Sprintf(
+			"I'll message you when %s is free. Make sure you've started a private chat with me",
+			date,
+		),
+		NoParseMode,
+	)
+	return nil
+}
+
+func listWatches(command Command) error {
+	watches, err := watch.WatchRepo.GetUserWatches(
+		context.Background(),
+		command.ChatID,
+		command.From.ID,
+		utils.GetStartOfWeek(utils.GetToday()),
+	)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch your watches", NoParseMode)
+		return err
+	}
+	if len(watches) == 0 {
+		reply(command, "You don't watch any days. Try /watch DD-MM-YYYY", NoParseMode)
+		return nil
+	}
+
+	dates := make([]string, 0, len(watches))
+	for _, w := range watches {
+		dates = append(dates, w.At.Format(utils.AssignDateFormat))
+	}
+	reply(command, "You are waiting for:\n"+strings.Join(dates, "\n"), NoParseMode)
+	return nil
+}
+
+func cancelWatch(command Command, raw string) error {
+	day, err := utils.ParseDate(raw)
+	if err != nil {
+		reply(command, watchUsage, NoParseMode)
+		return err
+	}
+	if isWeeklyChat(command.ChatID) {
+		day = utils.GetStartOfWeek(day)
+	}
+
+	watches, err := watch.WatchRepo.GetUserWatches(context.Background(), command.ChatID, command.From.ID, day)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch your watches", NoParseMode)
+		return err
+	}
+	for _, w := range watches {
+		if !w.At.Equal(day) {
+			continue
+		}
+		err = watch.WatchRepo.DeleteWatch(context.Background(), w.ID)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+			reply(command, "Failed to cancel watch", NoParseMode)
+			return err
+		}
+		reply(command, "Watch is cancelled", NoParseMode)
+		return nil
+	}
+	reply(command, "You don't watch that day", NoParseMode)
+	return nil
+}
+
+// Message everyone waiting for the day of freed assignment.
+// Whoever presses "Take it" first gets the duty.
+func notifyWatchers(as assignment.Assignment) {
+	if as.IsBackup() || as.IsShift() {
+		return
+	}
+
+	watches, err := watch.WatchRepo.GetWatchers(context.Background(), as.ChatID, as.At)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return
+	}
+	if len(watches) == 0 {
+		return
+	}
+
+	text := fmt.Sprintf("Duty %s in %s is free now", formatDutyTime(as), getChatTitle(as.ChatID))
+	for _, w := range watches {
+		if w.UserID == as.UserID {
+			continue
+		}
+		_, away, err := findAbsence(context.Background(), w.UserID, as.StartsAt, as.EndsAt)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+			continue
+		}
+		if away {
+			continue
+		}
+		msg := tgbot.NewMessage(w.UserID, text)
+		msg.ReplyMarkup = tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(
+			callbackButton("Take it", callback.Data{Action: callback.ActionAssign, View: callback.ViewWatch, ID: w.ID}),
+		))
+		_, err = bot.Send(msg)
+		if err != nil {
+			logger.Log.Warn().
+				Err(err).
+				Int64("user_id", w.UserID).
+				Msg("Unable to notify watcher")
+		}
+	}
+}
+
+// Assignment of the user for watched day.
+// Not ok if the user can't take it, the reason is shown in the message.
+func getWatchedSlot(command Command, w watch.Watch) (assignment.Assignment, bool, error) {
+	ctx := context.Background()
+	dutydate, err := parseDutyDate(w.ChatID, w.At.Format(utils.AssignDateFormat))
+	if err != nil {
+		editCallbackMessage(command, err.Error(), uuid.Nil)
+		return assignment.Assignment{}, false, watch.WatchRepo.DeleteWatch(ctx, w.ID)
+	}
+	startsAt, endsAt, err := getDutyBounds(w.ChatID, dutydate, "")
+	if err != nil {
+		editCallbackMessage(command, err.Error(), uuid.Nil)
+		return assignment.Assignment{}, false, nil
+	}
+
+	absent, ok, err := findAbsence(ctx, command.From.ID, startsAt, endsAt)
+	if err != nil {
+		return assignment.Assignment{}, false, err
+	}
+	if ok {
+		editCallbackMessage(
+			command,
+			fmt.Sprintf("You are away %s, try /away cancel first", formatAbsence(absent)),
+			uuid.Nil,
+		)
+		return assignment.Assignment{}, false, nil
+	}
+
+	return assignment.Assignment{
+		ID:        uuid.New(),
+		ChatID:    w.ChatID,
+		At:        dutydate,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		UserID:    command.From.ID,
+		Operator:  command.From.DisplayName(),
+		Role:      assignment.RolePrimary,
+		CreatedAt: utils.GetToday(),
+	}, true, nil
+}
+
+// "Take it" button of watch notification
+func takeWatchedSlot(command Command) error {
+	ctx := context.Background()
+	w, err := watch.WatchRepo.GetWatch(ctx, command.Callback.ID)
+	switch {
+	case errors.Is(err, watch.ErrNotFound):
+		editCallbackMessage(command, "This watch is over", uuid.Nil)
+		return nil
+	case err != nil:
+		return err
+	case w.UserID != command.From.ID:
+		return nil
+	default:
+	}
+
+	as, ok, err := getWatchedSlot(command, w)
+	if err != nil || !ok {
+		return err
+	}
+	change, err := assignment.AssignmentRepo.TakeSlot(ctx, as, getActor(command))
+	switch {
+	case errors.Is(err, assignment.ErrSlotTaken):
+		editCallbackMessage(command, "Somebody was faster, I'll keep watching", uuid.Nil)
+		return nil
+	case err != nil:
+		return err
+	default:
+	}
+
+	err = watch.WatchRepo.DeleteWatch(ctx, w.ID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+	}
+	refreshChatKeyboards(w.ChatID, 0)
+
+	editCallbackMessage(
+		command,
+		fmt.Sprintf("You are on duty %s in %s", formatDutyTime(as), getChatTitle(w.ChatID)),
+		uuid.Nil,
+	)
+	announceWithUndo(
+		w.ChatID,
+		fmt.Sprintf(
+			"%s took duty %s from waitlist",
+			mentionOperator(ctx, as),
+			html.EscapeString(formatDutyTime(as)),
+		),
+		HTMLParseMode,
+		change,
+	)
+	return nil
+}
@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/webhook"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+func manageWebhooks(command Command) error {
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can manage webhooks", NoParseMode)
+		return nil
+	}
+
+	args := strings.Fields(command.Arguments)
+	if len(args) == 0 {
+		return listWebhooks(command)
+	}
+
+	switch args[0] {
+	case "add":
+		return addWebhook(command, args[1:])
+	case "remove":
+		return removeWebhook(command, args[1:])
+	case "list":
+		return listWebhooks(command)
+	default:
+		reply(
+			command,
+			"Usage: /webhook [add url | remove url | list]",
+			NoParseMode,
+		)
+		return nil
+	}
+}
+
+func checkWebhookURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("'%s' doesn't look like http(s) URL", rawURL)
+	}
+	return u.String(), nil
+}
+
+// Length of generated webhook secret in bytes
+const webhookSecretSize = 32
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Secret is generated and sent to admin privately,
+// so other chat members never see it
+func addWebhook(command Command, args []string) error {
+	if len(args) != 1 {
+		reply(command, "Usage: /webhook add url", NoParseMode)
+		return nil
+	}
+
+	hookURL, err := checkWebhookURL(args[0])
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to add webhook", NoParseMode)
+		return err
+	}
+
+	hook := webhook.Webhook{
+		ID:        uuid.New(),
+		ChatID:    command.ChatID,
+		URL:       hookURL,
+		Secret:    secret,
+		CreatedAt: utils.GetToday(),
+	}
+	err = webhook.WebhookRepo.AddWebhook(context.Background(), hook)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to add webhook", NoParseMode)
+		return err
+	}
+
+	_, err = bot.Send(tgbot.NewMessage(
+		command.From.ID,
+		fmt.Sprintf("Signing secret of webhook %s in %s:\n%s", hook.URL, getChatTitle(command.ChatID), secret),
+	))
+	if err != nil {
+		logger.Log.Warn().Err(err).Int64("user_id", command.From.ID).Msg("Unable to send webhook secret")
+		err = webhook.WebhookRepo.DeleteWebhook(context.Background(), command.ChatID, hook.URL)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+		}
+		reply(command, "Start a private chat with me first, I'll send the webhook secret there", NoParseMode)
+		return nil
+	}
+
+	reply(command, fmt.Sprintf("Webhook %s added, secret is sent to you privately", hook.URL), NoParseMode)
+	return nil
+}
+
+func removeWebhook(command Command, args []string) error {
+	if len(args) != 1 {
+		reply(command, "Usage: /webhook remove url", NoParseMode)
+		return nil
+	}
+
+	hookURL, err := checkWebhookURL(args[0])
+	if err != nil {
+		reply(command, err.Error(), NoParseMode)
+		return err
+	}
+
+	err = webhook.WebhookRepo.DeleteWebhook(context.Background(), command.ChatID, hookURL)
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		reply(command, fmt.Sprintf("No webhook %s", hookURL), NoParseMode)
+		return nil
+	case err != nil:
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to remove webhook", NoParseMode)
+		return err
+	default:
+	}
+
+	reply(command, fmt.Sprintf("Webhook %s removed", hookURL), NoParseMode)
+	return nil
+}
+
+func listWebhooks(command Command) error {
+	hooks, err := webhook.WebhookRepo.GetWebhooks(context.Background(), command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch webhooks", NoParseMode)
+		return err
+	}
+
+	if len(hooks) == 0 {
+		reply(command, "No webhooks configured", NoParseMode)
+		return nil
+	}
+
+	urls := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		urls = append(urls, hook.URL)
+	}
+	reply(command, strings.Join(urls, "\n"), NoParseMode)
+	return nil
+}
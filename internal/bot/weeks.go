@@ -0,0 +1,261 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/callback"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/chat"
+	"github.com/FedoseevAlex/DutyBot/internal/events"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Number of weeks shown on keyboard of weekly chat
+const keyboardWeeks = 4
+
+// Chats hand over duty daily unless admins chose weekly rotation
+func isWeeklyChat(chatID int64) bool {
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), chatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return false
+	}
+	return settings.DutyPeriod == chat.DutyPeriodWeek
+}
+
+// Duty date from command arguments.
+// Weekly chats accept ISO week or any date of the week and get its monday.
+func parseDutyDate(chatID int64, arguments string) (time.Time, error) {
+	if !isWeeklyChat(chatID) {
+		return checkDate(arguments)
+	}
+
+	monday, ok, err := utils.ParseISOWeek(strings.TrimSpace(arguments))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !ok {
+		date, err := utils.ParseDate(arguments)
+		if err != nil {
+			return time.Time{}, err
+		}
+		monday = utils.GetStartOfWeek(date)
+	}
+
+	if !monday.Add(utils.WeekDuration).After(utils.GetToday()) {
+		return time.Time{}, fmt.Errorf("assignment is possible only for current or future weeks")
+	}
+	return monday, nil
+}
+
+// Week number and its days, e.g. "W43 19 Oct - 25 Oct"
+func formatWeek(monday time.Time) string {
+	_, week := monday.ISOWeek()
+	sunday := monday.AddDate(0, 0, utils.DaysInWeek-1)
+	return fmt.Sprintf("W%02d %s - %s", week, monday.Format("02 Jan"), sunday.Format("02 Jan"))
+}
+
+// Keyboard with a button per week for weekly chats
+func makeDutyWeeksButtons(chatID int64, from time.Time) tgbot.InlineKeyboardMarkup {
+	from = utils.GetStartOfWeek(from)
+	due := from.Add(utils.WeekDuration * keyboardWeeks)
+
+	assignments, err := assignment.AssignmentRepo.GetAssignmentsInRange(
+		context.Background(),
+		from,
+		due.Add(-utils.DayDuration),
+		chatID,
+	)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+	}
+	operators := make(map[time.Time]string, len(assignments))
+	for _, as := range assignments {
+		if as.IsWeek() && !as.IsBackup() {
+			operators[utils.GetDate(as.At)] = as.Operator
+		}
+	}
+
+	keyboard := make([][]tgbot.InlineKeyboardButton, 0, keyboardWeeks+1)
+	for monday := from; due.After(monday); monday = monday.Add(utils.WeekDuration) {
+		operator := operators[monday]
+		buttons := []tgbot.InlineKeyboardButton{callbackButton(
+			strings.TrimSpace(fmt.Sprintf("%s %s", formatWeek(monday), operator)),
+			callback.Data{Action: callback.ActionAssign, View: callback.ViewWeek, Date: monday},
+		)}
+		if operator != "" {
+			buttons = append(buttons, callbackButton(
+				"reset",
+				callback.Data{Action: callback.ActionReset, View: callback.ViewWeek, Date: monday},
+			))
+		}
+		keyboard = append(keyboard, tgbot.NewInlineKeyboardRow(buttons...))
+	}
+
+	keyboard = append(keyboard, tgbot.NewInlineKeyboardRow(
+		callbackButton("<", callback.Data{
+			Action: callback.ActionShow,
+			View:   callback.ViewWeek,
+			Date:   from.Add(-utils.WeekDuration * keyboardWeeks),
+		}),
+		callbackButton(">", callback.Data{
+			Action: callback.ActionShow,
+			View:   callback.ViewWeek,
+			Date:   due,
+		}),
+	))
+	return tgbot.NewInlineKeyboardMarkup(keyboard...)
+}
+
+// First working day of the week starting at monday.
+// Monday is used if calendar is unavailable.
+func getFirstWorkingDay(monday time.Time) (time.Time, bool) {
+	days, err := calendar.GetWorkingDays(monday, monday.AddDate(0, 0, utils.DaysInWeek-1))
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Couldn't get working days")
+		return monday, true
+	}
+
+	var first time.Time
+	for day := range days {
+		if first.IsZero() || day.Before(first) {
+			first = day
+		}
+	}
+	return first, !first.IsZero()
+}
+
+// Week duties are announced on the first working day of the week
+func announceWeekDuties() {
+	today := utils.GetToday()
+	first, ok := getFirstWorkingDay(utils.GetStartOfWeek(today))
+	if !ok || !first.Equal(today) {
+		return
+	}
+
+	assignments, err := assignment.AssignmentRepo.GetAssignmentsAtAllChats(context.Background(), today)
+	if err != nil {
+		logger.Log.Error().
+			Err(err).
+			Msg("announceWeekDuties job failed to get operators")
+		return
+	}
+
+	for _, as := range assignments {
+		if !as.IsWeek() || as.IsBackup() {
+			continue
+		}
+		announce(
+			as.ChatID,
+			fmt.Sprintf(
+				"%s is on duty this week%s",
+				mentionOperator(context.Background(), as),
+				backupNote(context.Background(), as),
+			),
+			HTMLParseMode,
+		)
+		events.Publish(events.NewEvent(events.DutyStarted, as.ChatID, as))
+	}
+}
+
+// Check duty period may be switched and tell why not otherwise
+func checkPeriodSwitch(command Command, period string) (bool, error) {
+	// Daily and weekly duties would overlap, so duties
+	// that are not over yet should be reset first
+	assigned, err := assignment.AssignmentRepo.GetAssignments(
+		context.Background(),
+		assignment.ResetFilter{ChatID: command.ChatID, From: utils.GetStartOfWeek(utils.GetToday())},
+	)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch assignments", NoParseMode)
+		return false, err
+	}
+	upcoming := 0
+	for _, as := range assigned {
+		if as.EndsAt.After(utils.GetNow()) {
+			upcoming++
+		}
+	}
+	if upcoming != 0 {
+		reply(
+			command,
+			fmt.Sprintf("There are %d upcoming duties, reset them before switching to %s", upcoming, period),
+			NoParseMode,
+		)
+		return false, nil
+	}
+
+	if period == chat.DutyPeriodWeek {
+		templates, err := getShiftTemplates(command.ChatID)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+			reply(command, "Couldn't fetch shifts", NoParseMode)
+			return false, err
+		}
+		if len(templates) != 0 {
+			reply(command, "Weekly duty can't be split into shifts, try /shifts reset first", NoParseMode)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// /period [day|week] shows or changes how long single duty lasts
+func setDutyPeriod(command Command) error {
+	settings, err := chat.ChatRepo.GetSettings(context.Background(), command.ChatID)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't fetch chat settings", NoParseMode)
+		return err
+	}
+
+	period := strings.TrimSpace(command.Arguments)
+	if period == "" {
+		reply(command, fmt.Sprintf("Duty lasts a %s", settings.DutyPeriod), NoParseMode)
+		return nil
+	}
+	if period != chat.DutyPeriodDay && period != chat.DutyPeriodWeek {
+		reply(command, "Usage: /period [day|week]", NoParseMode)
+		return nil
+	}
+
+	isAdmin, err := isChatAdmin(command)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Couldn't check your permissions", NoParseMode)
+		return err
+	}
+	if !isAdmin {
+		reply(command, "Only chat admins can change duty period", NoParseMode)
+		return nil
+	}
+
+	if period == settings.DutyPeriod {
+		reply(command, fmt.Sprintf("Duty lasts a %s already", period), NoParseMode)
+		return nil
+	}
+	allowed, err := checkPeriodSwitch(command, period)
+	if err != nil || !allowed {
+		return err
+	}
+
+	settings.DutyPeriod = period
+	err = chat.ChatRepo.SaveSettings(context.Background(), settings)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		reply(command, "Failed to save chat settings", NoParseMode)
+		return err
+	}
+	refreshChatKeyboards(command.ChatID, 0)
+
+	reply(command, fmt.Sprintf("Duty lasts a %s now", period), NoParseMode)
+	return nil
+}
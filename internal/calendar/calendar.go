@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/FedoseevAlex/DutyBot/internal/utils"
@@ -19,6 +20,39 @@ const (
 
 type TimeSet map[time.Time]struct{}
 
+// Kind of day in production calendar
+type DayType int
+
+const (
+	WorkingDay DayType = iota
+	Weekend
+	// Non working weekday, e.g. New Year
+	PublicHoliday
+)
+
+func (dt DayType) String() string {
+	switch dt {
+	case Weekend:
+		return "weekend"
+	case PublicHoliday:
+		return "holiday"
+	default:
+		return "working day"
+	}
+}
+
+// Type of date given working days around it (see GetWorkingDays).
+// Holiday falling on Saturday or Sunday is a weekend.
+func GetDayType(date time.Time, workingDays TimeSet) DayType {
+	if _, ok := workingDays[date]; ok {
+		return WorkingDay
+	}
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return Weekend
+	}
+	return PublicHoliday
+}
+
 func (ts TimeSet) Add(element time.Time) {
 	ts[element] = struct{}{}
 }
@@ -125,6 +159,11 @@ func GetWorkingDays(start time.Time, stop time.Time) (TimeSet, error) {
 		return nil, err
 	}
 
+	days := int(stop.Sub(start)/utils.DayDuration) + 1
+	if len(respData) < days {
+		return nil, fmt.Errorf("calendar has %d days instead of %d", len(respData), days)
+	}
+
 	calendar := TimeSet{}
 	for date, i := start, 0; !date.After(stop); date, i = date.Add(utils.DayDuration), i+1 {
 		if respData[i] == '1' {
@@ -135,3 +174,59 @@ func GetWorkingDays(start time.Time, stop time.Time) (TimeSet, error) {
 
 	return calendar, nil
 }
+
+// Calendars of current and next years may be corrected,
+// so they are fetched again after a day
+const calendarTTL = 24 * time.Hour
+
+type cachedYear struct {
+	days      TimeSet
+	fetchedAt time.Time
+}
+
+var cache = struct {
+	sync.Mutex
+	years map[int]cachedYear
+}{years: make(map[int]cachedYear)}
+
+// Same as GetWorkingDays, but calendar of whole years is fetched
+// and kept in memory. Stale calendar is used if service is unavailable.
+func GetCachedWorkingDays(start time.Time, stop time.Time) (TimeSet, error) {
+	result := TimeSet{}
+	for year := start.Year(); year <= stop.Year(); year++ {
+		days, err := getYearWorkingDays(year)
+		if err != nil {
+			return nil, err
+		}
+		for date := range days {
+			if !date.Before(start) && !date.After(stop) {
+				result.Add(date)
+			}
+		}
+	}
+	return result, nil
+}
+
+func getYearWorkingDays(year int) (TimeSet, error) {
+	now := time.Now()
+	cache.Lock()
+	cached, ok := cache.years[year]
+	cache.Unlock()
+	if ok && (year < now.Year() || now.Sub(cached.fetchedAt) < calendarTTL) {
+		return cached.days, nil
+	}
+
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	days, err := GetWorkingDays(first, first.AddDate(1, 0, -1))
+	if err != nil {
+		if ok {
+			return cached.days, nil
+		}
+		return nil, err
+	}
+
+	cache.Lock()
+	cache.years[year] = cachedYear{days: days, fetchedAt: now}
+	cache.Unlock()
+	return days, nil
+}
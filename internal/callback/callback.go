@@ -0,0 +1,209 @@
+// Package callback implements compact signed encoding
+// of inline keyboard callback data.
+//
+// Payload is binary, base64 encoded to fit into 64 bytes
+// telegram allows for callback data:
+//
+//	version | action | view | key | issued at | date | second date | flags | [uuid] | mac
+//
+// Lowest bit of flags tells if uuid is present, the rest hold shift position.
+// Key is a fingerprint of the secret payload was signed with,
+// so payloads signed before secret rotation are still accepted
+// while previous secret is configured.
+package callback
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Current protocol version. Bump it when payload layout changes.
+const Version = 1
+
+// Telegram limit for callback data
+const MaxSize = 64
+
+const (
+	headerSize = 13
+	macSize    = 8
+	flagHasID  = 1
+	// Shift position is kept in flags above flagHasID
+	shiftOffset = 1
+	MaxShift    = 0xff >> shiftOffset
+)
+
+type Action uint8
+
+const (
+	ActionIgnore Action = iota
+	ActionShow
+	ActionSelect
+	ActionAssign
+	ActionReset
+	ActionSwapFrom
+	ActionSwap
+	ActionGiveAway
+	ActionAck
+	ActionResolve
+	ActionCancel
+	ActionConfirm
+	ActionUndo
+)
+
+// Keyboard the payload belongs to
+type View uint8
+
+const (
+	ViewNone View = iota
+	ViewWeek
+	ViewMonth
+	ViewDashboard
+	ViewIncident
+	ViewReset
+	ViewUndo
+	ViewWatch
+	ViewShift
+)
+
+var (
+	ErrMalformed = errors.New("malformed callback data")
+	ErrVersion   = errors.New("unsupported callback data version")
+	ErrForged    = errors.New("callback data signature mismatch")
+	ErrExpired   = errors.New("callback data expired")
+)
+
+type Data struct {
+	Action Action
+	View   View
+	// Day the action is made for (e.g. shown week or selected day)
+	Date time.Time
+	// Second day of two day actions (e.g. swap)
+	SecondDate time.Time
+	// Position of shift the action is made for, 0 if not chosen
+	Shift int
+	// Assignment or incident ID
+	ID uuid.UUID
+	// When payload was made. Set by Encode.
+	IssuedAt time.Time
+}
+
+func fingerprint(secret string) byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[0]
+}
+
+func sign(secret string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil)[:macSize]
+}
+
+// Days since unix epoch. Zero time is encoded as 0.
+func encodeDate(date time.Time) uint16 {
+	if date.IsZero() {
+		return 0
+	}
+	return uint16(utils.GetDate(date).Unix() / int64(utils.DayDuration/time.Second))
+}
+
+func decodeDate(days uint16) time.Time {
+	if days == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(days)*int64(utils.DayDuration/time.Second), 0).UTC()
+}
+
+func encode(data Data, secret string, now time.Time) string {
+	payload := make([]byte, headerSize, headerSize+len(uuid.UUID{})+macSize)
+	payload[0] = Version
+	payload[1] = byte(data.Action)
+	payload[2] = byte(data.View)
+	payload[3] = fingerprint(secret)
+	binary.BigEndian.PutUint32(payload[4:8], uint32(now.Unix()))
+	binary.BigEndian.PutUint16(payload[8:10], encodeDate(data.Date))
+	binary.BigEndian.PutUint16(payload[10:12], encodeDate(data.SecondDate))
+	payload[12] = byte(data.Shift&MaxShift) << shiftOffset
+	if data.ID != uuid.Nil {
+		payload[12] |= flagHasID
+		payload = append(payload, data.ID[:]...)
+	}
+	payload = append(payload, sign(secret, payload)...)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decode(raw string, secrets []string, now time.Time, ttl time.Duration) (Data, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || len(payload) < headerSize+macSize {
+		return Data{}, ErrMalformed
+	}
+	if payload[0] != Version {
+		return Data{}, ErrVersion
+	}
+
+	body, mac := payload[:len(payload)-macSize], payload[len(payload)-macSize:]
+	signed := false
+	for _, secret := range secrets {
+		if secret != "" && fingerprint(secret) == body[3] && hmac.Equal(mac, sign(secret, body)) {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return Data{}, ErrForged
+	}
+
+	data := Data{
+		Action:     Action(body[1]),
+		View:       View(body[2]),
+		IssuedAt:   time.Unix(int64(binary.BigEndian.Uint32(body[4:8])), 0).UTC(),
+		Date:       decodeDate(binary.BigEndian.Uint16(body[8:10])),
+		SecondDate: decodeDate(binary.BigEndian.Uint16(body[10:12])),
+		Shift:      int(body[12] >> shiftOffset),
+	}
+	switch {
+	case body[12]&flagHasID == 0 && len(body) == headerSize:
+	case body[12]&flagHasID != 0 && len(body) == headerSize+len(uuid.UUID{}):
+		copy(data.ID[:], body[headerSize:])
+	default:
+		return Data{}, ErrMalformed
+	}
+
+	if ttl > 0 && now.Sub(data.IssuedAt) > ttl {
+		return Data{}, ErrExpired
+	}
+	return data, nil
+}
+
+// Secrets payloads are checked against, current one first.
+// Bot token is used if no secret is configured.
+func getSecrets() []string {
+	current := viper.GetString("CallbackSecret")
+	if current == "" {
+		current = viper.GetString("BotToken")
+	}
+	return []string{current, viper.GetString("CallbackPreviousSecret")}
+}
+
+// Encode and sign callback data with current secret
+func Encode(data Data) string {
+	return encode(data, getSecrets()[0], time.Now())
+}
+
+// Decode callback data checking version, signature and age
+func Decode(raw string) (Data, error) {
+	if strings.Contains(raw, " ") {
+		// Old "action args" format
+		return Data{}, ErrVersion
+	}
+	return decode(raw, getSecrets(), time.Now(), viper.GetDuration("CallbackTTL"))
+}
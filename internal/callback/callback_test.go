@@ -0,0 +1,73 @@
+package callback
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+func TestRoundTrip(t *testing.T) {
+	data := Data{
+		Action:     ActionSwap,
+		View:       ViewMonth,
+		Date:       time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC),
+		SecondDate: time.Date(2026, time.November, 2, 0, 0, 0, 0, time.UTC),
+		ID:         uuid.New(),
+		Shift:      3,
+	}
+
+	raw := encode(data, "secret", now)
+	assert.LessOrEqual(t, len(raw), MaxSize)
+
+	decoded, err := decode(raw, []string{"secret"}, now, time.Hour)
+	assert.NoError(t, err)
+	data.IssuedAt = now
+	assert.Equal(t, data, decoded)
+}
+
+func TestRoundTripWithoutID(t *testing.T) {
+	data := Data{Action: ActionShow, View: ViewWeek}
+
+	decoded, err := decode(encode(data, "secret", now), []string{"secret"}, now, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uuid.Nil, decoded.ID)
+	assert.True(t, decoded.Date.IsZero())
+}
+
+func TestRejectForged(t *testing.T) {
+	raw := encode(Data{Action: ActionAssign}, "secret", now)
+
+	_, err := decode(raw, []string{"other"}, now, 0)
+	assert.ErrorIs(t, err, ErrForged)
+
+	tampered := []byte(raw)
+	tampered[3] ^= 1
+	_, err = decode(string(tampered), []string{"secret"}, now, 0)
+	assert.Error(t, err)
+}
+
+func TestRejectExpired(t *testing.T) {
+	raw := encode(Data{Action: ActionAssign}, "secret", now)
+
+	_, err := decode(raw, []string{"secret"}, now.Add(2*time.Hour), time.Hour)
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestSecretRotation(t *testing.T) {
+	raw := encode(Data{Action: ActionAssign}, "old", now)
+
+	_, err := decode(raw, []string{"new", "old"}, now, 0)
+	assert.NoError(t, err)
+}
+
+func TestRejectOldFormat(t *testing.T) {
+	_, err := Decode("assign 20-10-2026")
+	assert.ErrorIs(t, err, ErrVersion)
+
+	_, err = decode("???", []string{"secret"}, now, 0)
+	assert.ErrorIs(t, err, ErrMalformed)
+}
@@ -4,6 +4,15 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Settings of optional features, bound after the core ones
+var featureBinders = []func() error{
+	bindWebhookConfig,
+	bindCallbackConfig,
+	bindAlertConfig,
+	bindScheduleConfig,
+	bindReportConfig,
+}
+
 // Public function to read config from standard location
 func ReadConfig() error {
 	if err := viper.BindEnv("DBConnectString", "DB_CONNECT_STRING"); err != nil {
@@ -42,6 +51,94 @@ func ReadConfig() error {
 		return err
 	}
 
+	for _, bind := range featureBinders {
+		if err := bind(); err != nil {
+			return err
+		}
+	}
+
+	viper.AutomaticEnv()
+	return nil
+}
+
+// Outbound webhooks
+func bindWebhookConfig() error {
+	if err := viper.BindEnv("WebhookSecret", "WEBHOOK_SECRET"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("WebhookRetries", 5)
+	if err := viper.BindEnv("WebhookRetries", "WEBHOOK_RETRIES"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("WebhookTimeout", "10s")
+	if err := viper.BindEnv("WebhookTimeout", "WEBHOOK_TIMEOUT"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Signing of inline keyboard buttons
+func bindCallbackConfig() error {
+	if err := viper.BindEnv("CallbackSecret", "CALLBACK_SECRET"); err != nil {
+		return err
+	}
+
+	if err := viper.BindEnv("CallbackPreviousSecret", "CALLBACK_PREVIOUS_SECRET"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("CallbackTTL", "720h")
+	if err := viper.BindEnv("CallbackTTL", "CALLBACK_TTL"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Alertmanager receiver and incidents raised by it
+func bindAlertConfig() error {
+	viper.SetDefault("AlertmanagerEnabled", false)
+	if err := viper.BindEnv("AlertmanagerEnabled", "ALERTMANAGER_ENABLED"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("AlertmanagerPath", "/alertmanager")
+	if err := viper.BindEnv("AlertmanagerPath", "ALERTMANAGER_PATH"); err != nil {
+		return err
+	}
+
+	if err := viper.BindEnv("AlertmanagerToken", "ALERTMANAGER_TOKEN"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("AlertmanagerListenAddress", "127.0.0.1:8080")
+	if err := viper.BindEnv("AlertmanagerListenAddress", "ALERTMANAGER_LISTEN_ADDRESS"); err != nil {
+		return err
+	}
+
+	if err := viper.BindEnv("AlertmanagerCertPath", "ALERTMANAGER_CERT_PATH"); err != nil {
+		return err
+	}
+
+	if err := viper.BindEnv("AlertmanagerKeyPath", "ALERTMANAGER_KEY_PATH"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("IncidentEscalationTimeout", "15m")
+	if err := viper.BindEnv("IncidentEscalationTimeout", "INCIDENT_ESCALATION_TIMEOUT"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("IncidentEscalationSchedule", "@every 1m")
+	if err := viper.BindEnv("IncidentEscalationSchedule", "INCIDENT_ESCALATION_SCHEDULE"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Announcements, shifts, recurring duties and schedule changes
+func bindScheduleConfig() error {
 	viper.SetDefault("DutyAnnounceSchedule", "0 7 * * *")
 	if err := viper.BindEnv("DutyAnnounceSchedule", "ANNOUNCE_SCHEDULE"); err != nil {
 		return err
@@ -52,6 +149,53 @@ func ReadConfig() error {
 		return err
 	}
 
-	viper.AutomaticEnv()
+	viper.SetDefault("ShiftAnnounceSchedule", "@every 1m")
+	if err := viper.BindEnv("ShiftAnnounceSchedule", "SHIFT_ANNOUNCE_SCHEDULE"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("RecurrenceSchedule", "0 3 * * *")
+	if err := viper.BindEnv("RecurrenceSchedule", "RECURRENCE_SCHEDULE"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("RecurrenceHorizonWeeks", 8)
+	if err := viper.BindEnv("RecurrenceHorizonWeeks", "RECURRENCE_HORIZON_WEEKS"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("UndoTimeout", "5m")
+	if err := viper.BindEnv("UndoTimeout", "UNDO_TIMEOUT"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("SwapRequestTimeout", "24h")
+	if err := viper.BindEnv("SwapRequestTimeout", "SWAP_REQUEST_TIMEOUT"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("ResetConfirmTimeout", "15m")
+	if err := viper.BindEnv("ResetConfirmTimeout", "RESET_CONFIRM_TIMEOUT"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Compensation ledger and monthly report
+func bindReportConfig() error {
+	viper.SetDefault("CompensationWeekend", 1)
+	if err := viper.BindEnv("CompensationWeekend", "COMPENSATION_WEEKEND"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("CompensationHoliday", 1)
+	if err := viper.BindEnv("CompensationHoliday", "COMPENSATION_HOLIDAY"); err != nil {
+		return err
+	}
+
+	viper.SetDefault("ReportSchedule", "0 9 1 * *")
+	if err := viper.BindEnv("ReportSchedule", "REPORT_SCHEDULE"); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,66 @@
+package absence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const absencesTableName = "absences"
+
+type AbsenceRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var AbsenceRepo AbsenceRepoer
+
+type AbsenceRepoer interface {
+	AddAbsence(ctx context.Context, absence Absence) error
+	DeleteAbsence(ctx context.Context, id uuid.UUID) error
+	// Absences of user ending on or after from
+	GetUserAbsences(ctx context.Context, userID int64, from time.Time) ([]Absence, error)
+	// Absences of every user overlapping from and to dates inclusive
+	GetAbsences(ctx context.Context, from, to time.Time) ([]Absence, error)
+}
+
+// Days user can't be on duty, e.g. vacation.
+// Absence belongs to user, not chat, so it applies to every chat.
+type Absence struct {
+	ID     uuid.UUID `db:"uuid"`
+	UserID int64     `db:"user_id"`
+	// First and last days of absence
+	StartsOn time.Time `db:"starts_on"`
+	EndsOn   time.Time `db:"ends_on"`
+	Reason   string    `db:"reason"`
+	// When absence was reported
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Absence covers any day between from and to inclusive
+func (a Absence) Overlaps(from, to time.Time) bool {
+	return !utils.GetDate(a.StartsOn).After(utils.GetDate(to)) &&
+		!utils.GetDate(a.EndsOn).Before(utils.GetDate(from))
+}
+
+// Absence covers any day of duty lasting from start until end
+func (a Absence) Conflicts(start, end time.Time) bool {
+	last := start
+	if end.After(start) {
+		last = end.Add(-time.Nanosecond)
+	}
+	return a.Overlaps(start, last)
+}
+
+func InitAbsenceRepo(ctx context.Context, dsn string) (AbsenceRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &AbsenceRepoData{conn: conn}
+	AbsenceRepo = result
+	return result, nil
+}
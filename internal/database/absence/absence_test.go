@@ -0,0 +1,37 @@
+package absence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, time.October, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestOverlaps(t *testing.T) {
+	a := Absence{StartsOn: day(10), EndsOn: day(12)}
+
+	assert.True(t, a.Overlaps(day(12), day(20)))
+	assert.True(t, a.Overlaps(day(1), day(10)))
+	assert.True(t, a.Overlaps(day(11), day(11)))
+	assert.False(t, a.Overlaps(day(13), day(20)))
+	assert.False(t, a.Overlaps(day(1), day(9)))
+}
+
+func TestConflicts(t *testing.T) {
+	a := Absence{StartsOn: day(10), EndsOn: day(12)}
+
+	// Whole day duty ends at midnight of the next day
+	assert.False(t, a.Conflicts(day(9), day(10)))
+	assert.True(t, a.Conflicts(day(12), day(13)))
+	// Night shift starting on the last day of absence
+	assert.True(t, a.Conflicts(day(12).Add(22*time.Hour), day(13).Add(6*time.Hour)))
+	// Week duty
+	assert.True(t, a.Conflicts(day(5), day(5).Add(utils.WeekDuration)))
+	assert.False(t, a.Conflicts(day(13), day(13).Add(utils.WeekDuration)))
+}
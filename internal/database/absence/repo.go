@@ -0,0 +1,106 @@
+package absence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/google/uuid"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotInserted = errors.New("pgx CommandTag is not INSERT")
+	ErrNotDeleted  = errors.New("pgx CommandTag is not DELETE")
+)
+
+var _ AbsenceRepoer = &AbsenceRepoData{}
+
+func (ar *AbsenceRepoData) AddAbsence(ctx context.Context, absence Absence) error {
+	sql, params, err := goqu.Insert(absencesTableName).Rows(absence).ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := ar.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Insert() {
+		return ErrNotInserted
+	}
+	return nil
+}
+
+func (ar *AbsenceRepoData) DeleteAbsence(ctx context.Context, id uuid.UUID) error {
+	sql, params, err := goqu.Delete(absencesTableName).
+		Where(goqu.Ex{"uuid": id.String()}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := ar.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Delete() {
+		return ErrNotDeleted
+	}
+	return nil
+}
+
+func (ar *AbsenceRepoData) GetUserAbsences(ctx context.Context, userID int64, from time.Time) ([]Absence, error) {
+	sql, params, err := goqu.From(absencesTableName).
+		Select(Absence{}).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("ends_on").Gte(from.Format(utils.DateFormat)),
+		).
+		Order(goqu.I("starts_on").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := ar.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Absence])
+}
+
+func (ar *AbsenceRepoData) GetAbsences(ctx context.Context, from, to time.Time) ([]Absence, error) {
+	sql, params, err := goqu.From(absencesTableName).
+		Select(Absence{}).
+		Where(
+			goqu.C("starts_on").Lte(to.Format(utils.DateFormat)),
+			goqu.C("ends_on").Gte(from.Format(utils.DateFormat)),
+		).
+		Order(goqu.I("starts_on").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := ar.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Absence])
+}
@@ -0,0 +1,42 @@
+package alert
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const alertRoutesTableName = "alert_routes"
+
+type AlertRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var AlertRepo AlertRepoer
+
+type AlertRepoer interface {
+	SetRoute(ctx context.Context, route Route) error
+	DeleteRoute(ctx context.Context, chatID int64, route string) error
+	GetRoute(ctx context.Context, route string) (Route, error)
+	GetRoutes(ctx context.Context, chatID int64) ([]Route, error)
+}
+
+type Route struct {
+	// Alertmanager receiver name or dutybot_route label value
+	Route string `db:"route"`
+	// Chat where alerts are posted
+	ChatID int64 `db:"chat_id"`
+	// When route was created
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func InitAlertRepo(ctx context.Context, dsn string) (AlertRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &AlertRepoData{conn: conn}
+	AlertRepo = result
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package alert
+
+import (
+	"context"
+	"errors"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ AlertRepoer = &AlertRepoData{}
+
+// errors
+var (
+	ErrNotFound   = errors.New("alert route not found")
+	ErrRouteTaken = errors.New("alert route belongs to another chat")
+)
+
+// Point route to the chat. Route of another chat is never moved,
+// it has to be deleted there first.
+func (ar *AlertRepoData) SetRoute(ctx context.Context, route Route) error {
+	sql, params, err := goqu.Insert(alertRoutesTableName).
+		Rows(route).
+		OnConflict(goqu.DoUpdate("route", goqu.Record{
+			"created_at": route.CreatedAt,
+		}).Where(goqu.I(alertRoutesTableName + ".chat_id").Eq(route.ChatID))).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := ar.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRouteTaken
+	}
+	return nil
+}
+
+func (ar *AlertRepoData) DeleteRoute(ctx context.Context, chatID int64, route string) error {
+	sql, params, err := goqu.Delete(alertRoutesTableName).
+		Where(goqu.Ex{
+			"chat_id": chatID,
+			"route":   route,
+		}).ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := ar.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (ar *AlertRepoData) GetRoute(ctx context.Context, route string) (Route, error) {
+	sql, params, err := goqu.From(alertRoutesTableName).
+		Select(Route{}).
+		Where(goqu.Ex{"route": route}).
+		ToSQL()
+	if err != nil {
+		return Route{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := ar.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return Route{}, err
+	}
+
+	result, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Route])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return Route{}, ErrNotFound
+	case err != nil:
+		return Route{}, err
+	default:
+	}
+	return result, nil
+}
+
+// Return all routes pointing to specified chat
+func (ar *AlertRepoData) GetRoutes(ctx context.Context, chatID int64) ([]Route, error) {
+	sql, params, err := goqu.From(alertRoutesTableName).
+		Select(Route{}).
+		Where(goqu.Ex{"chat_id": chatID}).
+		Order(goqu.I("route").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := ar.conn.Query(ctx, sql, params...)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Route{}, err
+	}
+	defer rows.Close()
+
+	routes, err := pgx.CollectRows(rows, pgx.RowToStructByName[Route])
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Route{}, err
+	}
+	return routes, nil
+}
+
+func (ar AlertRepoData) Close() error {
+	ar.conn.Close()
+	return nil
+}
@@ -2,13 +2,36 @@ package assignment
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const (
+	assignmentsTableName      = "assignments"
+	assignmentEventsTableName = "assignment_events"
 )
 
-const assignmentsTableName = "assignments"
+// Kinds of schedule changes recorded in audit log
+const (
+	EventCreate = "create"
+	EventDelete = "delete"
+	EventSwap   = "swap"
+)
+
+// Events recorded before snapshots were kept can't be undone
+var ErrNoSnapshot = errors.New("event has no assignment snapshot")
+
+// Operator roles. Secondary operator backs up the primary one.
+const (
+	RolePrimary   = "primary"
+	RoleSecondary = "secondary"
+)
 
 type AssignmentRepoData struct {
 	conn *pgxpool.Pool
@@ -17,28 +40,200 @@ type AssignmentRepoData struct {
 var AssignmentRepo AssignmentRepoer
 
 type AssignmentRepoer interface {
-	AddAssignment(ctx context.Context, as Assignment) error
-	DeleteAssignment(ctx context.Context, id uuid.UUID) error
+	AddAssignment(ctx context.Context, as Assignment, actor Actor) (uuid.UUID, error)
+	TakeSlot(ctx context.Context, as Assignment, actor Actor) (uuid.UUID, error)
+	TakeSlots(ctx context.Context, assignments []Assignment, actor Actor) (uuid.UUID, error)
+	DeleteAssignment(ctx context.Context, id uuid.UUID, actor Actor) (uuid.UUID, error)
+	DeleteRecurrenceAssignments(ctx context.Context, recurrenceID uuid.UUID, from time.Time, actor Actor) (int, error)
+	DeleteAssignmentsByID(
+		ctx context.Context,
+		chatID int64,
+		ids []uuid.UUID,
+		from time.Time,
+		actor Actor,
+	) ([]Assignment, uuid.UUID, error)
+	SwapAssignments(ctx context.Context, first, second uuid.UUID, actor Actor) (uuid.UUID, error)
+	UndoChange(ctx context.Context, change uuid.UUID, actor Actor) ([]Assignment, error)
+	GetAssignmentEvents(ctx context.Context, filter EventFilter) ([]AssignmentEvent, error)
 	GetAssignmentSchedule(ctx context.Context, due time.Time, chatID int64) ([]Assignment, error)
 	GetAssignmentScheduleAllChats(ctx context.Context, due time.Time) ([]Assignment, error)
-	GetAssignmentByDate(ctx context.Context, due time.Time, chatID int64) (Assignment, error)
-	GetFreeSlots(ctx context.Context, due time.Time, chatID int64) ([]time.Time, error)
+	GetAssignmentByDate(ctx context.Context, due time.Time, chatID int64, role string) (Assignment, error)
+	GetAssignmentAt(ctx context.Context, moment time.Time, chatID int64, role string) (Assignment, error)
+	GetShiftsStartingBetween(ctx context.Context, from, to time.Time) ([]Assignment, error)
+	GetAssignmentsAtAllChats(ctx context.Context, moment time.Time) ([]Assignment, error)
+	GetAssignment(ctx context.Context, id uuid.UUID) (Assignment, error)
+	GetOperatorAssignments(ctx context.Context, userID int64, from time.Time) ([]Assignment, error)
+	GetFreeSlots(ctx context.Context, due time.Time, chatID int64, weekly bool) ([]time.Time, error)
 	GetAllChats(ctx context.Context) ([]int64, error)
+	GetOperatorChats(ctx context.Context, userID int64) ([]int64, error)
+	GetChatOperators(ctx context.Context, chatID int64, since time.Time) ([]Operator, error)
 	GetSchedule(ctx context.Context, from, due time.Time, chatID int64, filterHolidays bool) ([]Assignment, error)
+	GetAssignmentsInRange(ctx context.Context, from, to time.Time, chatID int64) ([]Assignment, error)
+	GetAssignments(ctx context.Context, filter ResetFilter) ([]Assignment, error)
 }
 
 type Assignment struct {
-	ID uuid.UUID `db:"uuid"`
+	ID uuid.UUID `db:"uuid" json:"uuid"`
 	// Assignment day
-	At time.Time `db:"at"`
+	At time.Time `db:"at" json:"at"`
 	// From which chat assignment came from
-	ChatID int64 `db:"chat_id"`
-	// Assignee for duty
-	Operator string `db:"operator"`
+	ChatID int64 `db:"chat_id" json:"chat_id"`
+	// Telegram ID of assignee (see users table)
+	UserID int64 `db:"user_id" json:"user_id"`
+	// Assignee name, kept in sync with users table
+	Operator string `db:"operator" json:"operator"`
+	// RolePrimary or RoleSecondary
+	Role string `db:"role" json:"role"`
+	// Duty bounds. Whole At day unless chat uses shifts.
+	StartsAt time.Time `db:"starts_at" json:"starts_at"`
+	EndsAt   time.Time `db:"ends_at" json:"ends_at"`
+	// Recurrence rule assignment was made by, if any
+	RecurrenceID *uuid.UUID `db:"recurrence_uuid" json:"recurrence_uuid"`
 	// When assignment was created
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Who changes the schedule
+type Actor struct {
+	// Telegram user ID
+	ID   int64
+	Name string
+}
+
+// Member of chat duty roster
+type Operator struct {
+	UserID int64  `db:"user_id"`
+	Name   string `db:"operator"`
+}
+
+// Assignment covers part of the day (see shift templates)
+func (as Assignment) IsShift() bool {
+	if as.StartsAt.IsZero() {
+		return false
+	}
+	duration := as.EndsAt.Sub(as.StartsAt)
+	return !as.StartsAt.Equal(as.At) || (duration != utils.DayDuration && duration != utils.WeekDuration)
+}
+
+// Assignments are the same duty of the same operator
+func (as Assignment) Same(other Assignment) bool {
+	sameRecurrence := as.RecurrenceID == nil && other.RecurrenceID == nil ||
+		as.RecurrenceID != nil && other.RecurrenceID != nil && *as.RecurrenceID == *other.RecurrenceID
+	return as.ID == other.ID &&
+		as.ChatID == other.ChatID &&
+		as.At.Equal(other.At) &&
+		as.UserID == other.UserID &&
+		as.Operator == other.Operator &&
+		as.Role == other.Role &&
+		as.StartsAt.Equal(other.StartsAt) &&
+		as.EndsAt.Equal(other.EndsAt) &&
+		sameRecurrence
+}
+
+func (as Assignment) IsBackup() bool {
+	return as.Role == RoleSecondary
+}
+
+// Assignment covers whole week starting at At
+func (as Assignment) IsWeek() bool {
+	return as.StartsAt.Equal(as.At) && as.EndsAt.Sub(as.StartsAt) == utils.WeekDuration
+}
+
+// Days covered by assignment: every day of the week
+// for weekly duty and At day otherwise
+func (as Assignment) Days() []time.Time {
+	date := utils.GetDate(as.At)
+	if !as.IsWeek() {
+		return []time.Time{date}
+	}
+	days := make([]time.Time, 0, utils.DaysInWeek)
+	for i := 0; i < utils.DaysInWeek; i++ {
+		days = append(days, date.Add(utils.DayDuration*time.Duration(i)))
+	}
+	return days
+}
+
+// Record of schedule change
+type AssignmentEvent struct {
+	ID uuid.UUID `db:"uuid"`
+	// Events made by one change (e.g. both sides of swap) share it
+	ChangeID uuid.UUID `db:"change_uuid"`
+	// Changed assignment
+	AssignmentID uuid.UUID `db:"assignment_uuid"`
+	// One of create, delete or swap
+	Kind   string    `db:"kind"`
+	ChatID int64     `db:"chat_id"`
+	At     time.Time `db:"at"`
+	// Assignee after the change (before it for deletions)
+	Operator string `db:"operator"`
+	// Who made the change
+	Actor string `db:"actor"`
+	// Telegram ID of who made the change, 0 for changes recorded before IDs were kept
+	ActorID int64 `db:"actor_id"`
+	// JSON of assignment after the change (before it for deletions)
+	Snapshot []byte `db:"assignment"`
+	// Assignment was created or deleted by undo of another change
+	Undo bool `db:"undo"`
+	// When change was made
 	CreatedAt time.Time `db:"created_at"`
 }
 
+// Changed assignment as it was recorded
+func (e AssignmentEvent) Assignment() (Assignment, error) {
+	var as Assignment
+	if len(e.Snapshot) == 0 {
+		return as, ErrNoSnapshot
+	}
+	err := json.Unmarshal(e.Snapshot, &as)
+	return as, err
+}
+
+// Duty was taken away from its operator, not just reverted by undo
+func (e AssignmentEvent) IsReset() bool {
+	return e.Kind == EventDelete && !e.Undo
+}
+
+// Change was made by user with given Telegram ID
+func (e AssignmentEvent) MadeBy(userID int64) bool {
+	return e.ActorID != 0 && e.ActorID == userID
+}
+
+// Assignment is still as the event left it.
+// Events without snapshot are never current.
+func (e AssignmentEvent) IsCurrent(as Assignment) (bool, error) {
+	recorded, err := e.Assignment()
+	switch {
+	case errors.Is(err, ErrNoSnapshot):
+		return false, nil
+	case err != nil:
+		return false, err
+	default:
+	}
+	return recorded.Same(as), nil
+}
+
+// Filter for audit log. Zero values are not used for filtering.
+type EventFilter struct {
+	ChatID int64
+	// Events of a single change
+	ChangeID uuid.UUID
+	// Range of changed assignment dates
+	From time.Time
+	To   time.Time
+	Kind string
+	// Return only this number of the latest events
+	Limit uint
+}
+
+// Filter for bulk reset. Zero values except ChatID are not used for filtering.
+type ResetFilter struct {
+	ChatID int64
+	UserID int64
+	// Range of assignment dates, both inclusive
+	From time.Time
+	To   time.Time
+}
+
 func InitAssignmentRepo(ctx context.Context, dsn string) (AssignmentRepoer, error) {
 	conn, err := pgxpool.New(ctx, dsn)
 	if err != nil {
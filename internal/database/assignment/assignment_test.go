@@ -0,0 +1,81 @@
+package assignment
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func snapshotEvent(t *testing.T, as Assignment) AssignmentEvent {
+	snapshot, err := json.Marshal(as)
+	assert.NoError(t, err)
+	return AssignmentEvent{AssignmentID: as.ID, Operator: as.Operator, Snapshot: snapshot}
+}
+
+func TestEventMadeBy(t *testing.T) {
+	event := AssignmentEvent{Actor: "Alice", ActorID: 42}
+	assert.True(t, event.MadeBy(42))
+	assert.False(t, event.MadeBy(43))
+
+	// Names are not checked, only IDs
+	event = AssignmentEvent{Actor: "Alice"}
+	assert.False(t, event.MadeBy(0))
+	assert.False(t, event.MadeBy(42))
+}
+
+func TestEventIsCurrent(t *testing.T) {
+	day := time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)
+	recurrenceID := uuid.New()
+	as := Assignment{
+		ID:           uuid.New(),
+		At:           day,
+		ChatID:       1,
+		UserID:       42,
+		Operator:     "Alice",
+		Role:         RolePrimary,
+		StartsAt:     day.Add(8 * time.Hour),
+		EndsAt:       day.Add(20 * time.Hour),
+		RecurrenceID: &recurrenceID,
+		CreatedAt:    day,
+	}
+	event := snapshotEvent(t, as)
+
+	current, err := event.IsCurrent(as)
+	assert.NoError(t, err)
+	assert.True(t, current)
+
+	// Same moments in other zone and creation time don't matter
+	same := as
+	moscow := time.FixedZone("MSK", 3*60*60)
+	same.StartsAt = as.StartsAt.In(moscow)
+	same.CreatedAt = day.AddDate(0, 0, 1)
+	sameRecurrence := recurrenceID
+	same.RecurrenceID = &sameRecurrence
+	current, err = event.IsCurrent(same)
+	assert.NoError(t, err)
+	assert.True(t, current)
+
+	edits := map[string]func(as *Assignment){
+		"operator": func(as *Assignment) { as.UserID, as.Operator = 43, "Bob" },
+		"role":     func(as *Assignment) { as.Role = RoleSecondary },
+		"start":    func(as *Assignment) { as.StartsAt = as.StartsAt.Add(time.Hour) },
+		"end":      func(as *Assignment) { as.EndsAt = as.EndsAt.Add(time.Hour) },
+		"day":      func(as *Assignment) { as.At = as.At.AddDate(0, 0, 1) },
+		"rule":     func(as *Assignment) { as.RecurrenceID = nil },
+	}
+	for name, edit := range edits {
+		changed := as
+		edit(&changed)
+		current, err = event.IsCurrent(changed)
+		assert.NoError(t, err, name)
+		assert.False(t, current, name)
+	}
+
+	// Events recorded before snapshots were kept can't be checked
+	current, err = AssignmentEvent{AssignmentID: as.ID, Operator: as.Operator}.IsCurrent(as)
+	assert.NoError(t, err)
+	assert.False(t, current)
+}
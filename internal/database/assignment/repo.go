@@ -1,7 +1,9 @@
 package assignment
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"sort"
 	"time"
@@ -10,6 +12,7 @@ import (
 	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
 
 	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/events"
 	"github.com/FedoseevAlex/DutyBot/internal/logger"
 	"github.com/FedoseevAlex/DutyBot/internal/utils"
 
@@ -25,16 +28,75 @@ var _ AssignmentRepoer = &AssignmentRepoData{}
 var (
 	ErrNotInserted = errors.New("pgx CommandTag is not INSERT")
 	ErrNotDeleted  = errors.New("pgx CommandTag is not DELETE")
+	ErrNotFound    = errors.New("assignment not found")
+	ErrNotUndone   = errors.New("schedule has changed since, change can't be undone")
+	ErrSlotTaken   = errors.New("slot is already taken")
 )
 
-func (asr *AssignmentRepoData) AddAssignment(ctx context.Context, as Assignment) error {
+// Append schedule change to audit log within transaction
+func insertEvent(ctx context.Context, tx pgx.Tx, change uuid.UUID, kind string, as Assignment, actor Actor) error {
+	event, err := newEvent(change, kind, as, actor)
+	if err != nil {
+		return err
+	}
+	return saveEvent(ctx, tx, event)
+}
+
+// Same as insertEvent for assignments created or deleted by undo
+func insertUndoEvent(ctx context.Context, tx pgx.Tx, change uuid.UUID, kind string, as Assignment, actor Actor) error {
+	event, err := newEvent(change, kind, as, actor)
+	if err != nil {
+		return err
+	}
+	event.Undo = true
+	return saveEvent(ctx, tx, event)
+}
+
+func newEvent(change uuid.UUID, kind string, as Assignment, actor Actor) (AssignmentEvent, error) {
+	snapshot, err := json.Marshal(as)
+	if err != nil {
+		return AssignmentEvent{}, err
+	}
+	return AssignmentEvent{
+		ID:           uuid.New(),
+		ChangeID:     change,
+		AssignmentID: as.ID,
+		Kind:         kind,
+		ChatID:       as.ChatID,
+		At:           as.At,
+		Operator:     as.Operator,
+		Actor:        actor.Name,
+		ActorID:      actor.ID,
+		Snapshot:     snapshot,
+		CreatedAt:    time.Now().UTC(),
+	}, nil
+}
+
+func saveEvent(ctx context.Context, tx pgx.Tx, event AssignmentEvent) error {
+	sql, params, err := goqu.Insert(assignmentEventsTableName).Rows(event).ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := tx.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Insert() {
+		return ErrNotInserted
+	}
+	return nil
+}
+
+func insertAssignment(ctx context.Context, tx pgx.Tx, as Assignment) error {
 	sql, params, err := goqu.Insert(assignmentsTableName).Rows(as).ToSQL()
 	if err != nil {
 		return err
 	}
 	logger.Log.Debug().Str("sql", sql).Send()
 
-	result, err := asr.conn.Exec(ctx, sql, params...)
+	result, err := tx.Exec(ctx, sql, params...)
 	if err != nil {
 		return err
 	}
@@ -44,23 +106,565 @@ func (asr *AssignmentRepoData) AddAssignment(ctx context.Context, as Assignment)
 	return nil
 }
 
-func (asr *AssignmentRepoData) DeleteAssignment(ctx context.Context, uid uuid.UUID) error {
+// Returns ID of the change to undo it
+func (asr *AssignmentRepoData) AddAssignment(ctx context.Context, as Assignment, actor Actor) (uuid.UUID, error) {
+	change := uuid.New()
+	err := pgx.BeginFunc(ctx, asr.conn, func(tx pgx.Tx) error {
+		if err := insertAssignment(ctx, tx, as); err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, change, EventCreate, as, actor)
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	events.Publish(events.NewEvent(events.AssignmentCreated, as.ChatID, as))
+	return change, nil
+}
+
+// Add assignment unless its slot is taken, so of several
+// concurrent takers only the first one wins.
+// Returns ID of the change to undo it.
+func (asr *AssignmentRepoData) TakeSlot(ctx context.Context, as Assignment, actor Actor) (uuid.UUID, error) {
+	return asr.TakeSlots(ctx, []Assignment{as}, actor)
+}
+
+// Serialize everybody who fills slots of the chat until commit,
+// so slot checked by isSlotFree stays free until it is taken
+func lockChatSlots(ctx context.Context, tx pgx.Tx, chatID int64) error {
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", chatID)
+	return err
+}
+
+// Add assignments of one chat as a single change. Nothing is added
+// if any of their slots is taken.
+// Returns ID of the change to undo it.
+func (asr *AssignmentRepoData) TakeSlots(
+	ctx context.Context,
+	assignments []Assignment,
+	actor Actor,
+) (uuid.UUID, error) {
+	if len(assignments) == 0 {
+		return uuid.Nil, nil
+	}
+	change := uuid.New()
+	err := pgx.BeginFunc(ctx, asr.conn, func(tx pgx.Tx) error {
+		if err := lockChatSlots(ctx, tx, assignments[0].ChatID); err != nil {
+			return err
+		}
+		for _, as := range assignments {
+			free, err := isSlotFree(ctx, tx, as)
+			if err != nil {
+				return err
+			}
+			if !free {
+				return ErrSlotTaken
+			}
+			if err := insertAssignment(ctx, tx, as); err != nil {
+				return err
+			}
+			if err := insertEvent(ctx, tx, change, EventCreate, as, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	for _, as := range assignments {
+		events.Publish(events.NewEvent(events.AssignmentCreated, as.ChatID, as))
+	}
+	return change, nil
+}
+
+func deleteAssignment(ctx context.Context, tx pgx.Tx, uid uuid.UUID) (Assignment, error) {
 	sql, params, err := goqu.Delete(assignmentsTableName).
 		Where(goqu.Ex{
 			"uuid": uid.String(),
-		}).ToSQL()
+		}).
+		Returning(goqu.Star()).
+		ToSQL()
 	if err != nil {
+		return Assignment{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+	rows, err := tx.Query(ctx, sql, params...)
+	if err != nil {
+		return Assignment{}, err
+	}
+
+	as, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Assignment])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return Assignment{}, ErrNotDeleted
+	case err != nil:
+		return Assignment{}, err
+	default:
+	}
+	return as, nil
+}
+
+// Returns ID of the change to undo it
+func (asr *AssignmentRepoData) DeleteAssignment(ctx context.Context, uid uuid.UUID, actor Actor) (uuid.UUID, error) {
+	var as Assignment
+	change := uuid.New()
+	err := pgx.BeginFunc(ctx, asr.conn, func(tx pgx.Tx) error {
+		var err error
+		as, err = deleteAssignment(ctx, tx, uid)
+		if err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, change, EventDelete, as, actor)
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	events.Publish(events.NewEvent(events.AssignmentDeleted, as.ChatID, as))
+	return change, nil
+}
+
+// Delete assignments made by recurrence rule on and after from date
+func (asr *AssignmentRepoData) DeleteRecurrenceAssignments(
+	ctx context.Context,
+	recurrenceID uuid.UUID,
+	from time.Time,
+	actor Actor,
+) (int, error) {
+	deleted, _, err := asr.deleteWhere(
+		ctx,
+		[]exp.Expression{
+			goqu.C("recurrence_uuid").Eq(recurrenceID.String()),
+			goqu.C("at").Gte(from.Format(utils.DateFormat)),
+		},
+		actor,
+	)
+	return len(deleted), err
+}
+
+func (f ResetFilter) where() []exp.Expression {
+	where := []exp.Expression{goqu.C("chat_id").Eq(f.ChatID)}
+	if f.UserID != 0 {
+		where = append(where, goqu.C("user_id").Eq(f.UserID))
+	}
+	if !f.From.IsZero() {
+		where = append(where, goqu.C("at").Gte(f.From.Format(utils.DateFormat)))
+	}
+	if !f.To.IsZero() {
+		where = append(where, goqu.C("at").Lte(f.To.Format(utils.DateFormat)))
+	}
+	return where
+}
+
+// Delete assignments of chat with given IDs in one transaction.
+// Assignments that are already gone or start before from are skipped.
+// Returns deleted assignments and ID of the change to undo it.
+func (asr *AssignmentRepoData) DeleteAssignmentsByID(
+	ctx context.Context,
+	chatID int64,
+	ids []uuid.UUID,
+	from time.Time,
+	actor Actor,
+) ([]Assignment, uuid.UUID, error) {
+	if len(ids) == 0 {
+		return nil, uuid.Nil, nil
+	}
+	uuids := make([]string, 0, len(ids))
+	for _, id := range ids {
+		uuids = append(uuids, id.String())
+	}
+	return asr.deleteWhere(ctx, []exp.Expression{
+		goqu.C("chat_id").Eq(chatID),
+		goqu.C("uuid").In(uuids),
+		goqu.C("at").Gte(from.Format(utils.DateFormat)),
+	}, actor)
+}
+
+func (asr *AssignmentRepoData) deleteWhere(
+	ctx context.Context,
+	where []exp.Expression,
+	actor Actor,
+) ([]Assignment, uuid.UUID, error) {
+	sql, params, err := goqu.Delete(assignmentsTableName).
+		Where(where...).
+		Returning(goqu.Star()).
+		ToSQL()
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	var deleted []Assignment
+	change := uuid.New()
+	err = pgx.BeginFunc(ctx, asr.conn, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, sql, params...)
+		if err != nil {
+			return err
+		}
+		deleted, err = pgx.CollectRows(rows, pgx.RowToStructByName[Assignment])
+		if err != nil {
+			return err
+		}
+		for _, as := range deleted {
+			if err := insertEvent(ctx, tx, change, EventDelete, as, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	for _, as := range deleted {
+		events.Publish(events.NewEvent(events.AssignmentDeleted, as.ChatID, as))
+	}
+	return deleted, change, nil
+}
+
+func getAssignmentForUpdate(ctx context.Context, tx pgx.Tx, uid uuid.UUID) (Assignment, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select(Assignment{}).
+		Where(goqu.Ex{"uuid": uid.String()}).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return Assignment{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := tx.Query(ctx, sql, params...)
+	if err != nil {
+		return Assignment{}, err
+	}
+
+	as, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Assignment])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return Assignment{}, ErrNotFound
+	case err != nil:
+		return Assignment{}, err
+	default:
+	}
+	return as, nil
+}
+
+func setOperator(ctx context.Context, tx pgx.Tx, as Assignment) error {
+	sql, params, err := goqu.Update(assignmentsTableName).
+		Set(goqu.Record{
+			"user_id":  as.UserID,
+			"operator": as.Operator,
+		}).
+		Where(goqu.Ex{"uuid": as.ID.String()}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = tx.Exec(ctx, sql, params...)
+	return err
+}
+
+func swapAssignments(
+	ctx context.Context,
+	tx pgx.Tx,
+	change uuid.UUID,
+	swapped [2]Assignment,
+	actor Actor,
+) ([2]Assignment, error) {
+	swapped[0].UserID, swapped[1].UserID = swapped[1].UserID, swapped[0].UserID
+	swapped[0].Operator, swapped[1].Operator = swapped[1].Operator, swapped[0].Operator
+
+	for _, as := range swapped {
+		if err := setOperator(ctx, tx, as); err != nil {
+			return swapped, err
+		}
+		if err := insertEvent(ctx, tx, change, EventSwap, as, actor); err != nil {
+			return swapped, err
+		}
+	}
+	return swapped, nil
+}
+
+// Exchange operators of two assignments.
+// Returns ID of the change to undo it.
+func (asr *AssignmentRepoData) SwapAssignments(
+	ctx context.Context,
+	first uuid.UUID,
+	second uuid.UUID,
+	actor Actor,
+) (uuid.UUID, error) {
+	var swapped [2]Assignment
+	change := uuid.New()
+	err := pgx.BeginFunc(ctx, asr.conn, func(tx pgx.Tx) error {
+		// Lock rows in the same order whatever the order of arguments
+		// so that opposite swaps don't deadlock
+		ids := []uuid.UUID{first, second}
+		if bytes.Compare(second[:], first[:]) < 0 {
+			ids[0], ids[1] = second, first
+		}
+		locked := make(map[uuid.UUID]Assignment, len(ids))
+		for _, uid := range ids {
+			as, err := getAssignmentForUpdate(ctx, tx, uid)
+			if err != nil {
+				return err
+			}
+			locked[uid] = as
+		}
+		swapped = [2]Assignment{locked[first], locked[second]}
+
+		var err error
+		swapped, err = swapAssignments(ctx, tx, change, swapped, actor)
 		return err
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	for _, as := range swapped {
+		events.Publish(events.NewEvent(events.AssignmentSwapped, as.ChatID, as))
+	}
+	return change, nil
+}
+
+// Lock assignment and make sure it wasn't changed after the event
+func getUnchangedAssignment(ctx context.Context, tx pgx.Tx, event AssignmentEvent) (Assignment, error) {
+	as, err := getAssignmentForUpdate(ctx, tx, event.AssignmentID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return Assignment{}, ErrNotUndone
+	case err != nil:
+		return Assignment{}, err
+	default:
+	}
+	current, err := event.IsCurrent(as)
+	if err != nil {
+		return Assignment{}, err
+	}
+	if !current {
+		return Assignment{}, ErrNotUndone
+	}
+	return as, nil
+}
+
+// Check nobody else holds the slot of assignment: neither its role
+// nor its operator is taken at overlapping time.
+// Call under lockChatSlots to keep the answer valid until commit.
+func isSlotFree(ctx context.Context, tx pgx.Tx, as Assignment) (bool, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select(goqu.COUNT(goqu.Star())).
+		Where(
+			goqu.C("chat_id").Eq(as.ChatID),
+			goqu.Or(
+				goqu.C("role").Eq(as.Role),
+				goqu.C("user_id").Eq(as.UserID),
+			),
+			goqu.C("starts_at").Lt(as.EndsAt),
+			goqu.C("ends_at").Gt(as.StartsAt),
+		).
+		ToSQL()
+	if err != nil {
+		return false, err
 	}
 	logger.Log.Debug().Str("sql", sql).Send()
-	result, err := asr.conn.Exec(ctx, sql, params...)
+
+	rows, err := tx.Query(ctx, sql, params...)
+	if err != nil {
+		return false, err
+	}
+	count, err := pgx.CollectOneRow(rows, pgx.RowTo[int64])
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// Delete assignment created by the change unless it was changed after
+func undoCreate(
+	ctx context.Context,
+	tx pgx.Tx,
+	undo uuid.UUID,
+	event AssignmentEvent,
+	actor Actor,
+) (events.Event, error) {
+	if _, err := getUnchangedAssignment(ctx, tx, event); err != nil {
+		return events.Event{}, err
+	}
+	as, err := deleteAssignment(ctx, tx, event.AssignmentID)
+	if err != nil {
+		return events.Event{}, err
+	}
+	if err := insertUndoEvent(ctx, tx, undo, EventDelete, as, actor); err != nil {
+		return events.Event{}, err
+	}
+	return events.NewEvent(events.AssignmentDeleted, as.ChatID, as), nil
+}
+
+// Restore assignment deleted by the change unless its slot was taken after
+func undoDelete(
+	ctx context.Context,
+	tx pgx.Tx,
+	undo uuid.UUID,
+	event AssignmentEvent,
+	actor Actor,
+) (events.Event, error) {
+	as, err := event.Assignment()
+	switch {
+	case errors.Is(err, ErrNoSnapshot):
+		return events.Event{}, ErrNotUndone
+	case err != nil:
+		return events.Event{}, err
+	default:
+	}
+	free, err := isSlotFree(ctx, tx, as)
+	if err != nil {
+		return events.Event{}, err
+	}
+	if !free {
+		return events.Event{}, ErrNotUndone
+	}
+	if err := insertAssignment(ctx, tx, as); err != nil {
+		return events.Event{}, err
+	}
+	if err := insertUndoEvent(ctx, tx, undo, EventCreate, as, actor); err != nil {
+		return events.Event{}, err
+	}
+	return events.NewEvent(events.AssignmentCreated, as.ChatID, as), nil
+}
+
+// Swap back both sides of swap made by the change
+func undoSwap(
+	ctx context.Context,
+	tx pgx.Tx,
+	undo uuid.UUID,
+	swapped []Assignment,
+	actor Actor,
+) ([]events.Event, error) {
+	if len(swapped) == 0 {
+		return nil, nil
+	}
+	if len(swapped) != 2 {
+		return nil, ErrNotUndone
+	}
+	restored, err := swapAssignments(ctx, tx, undo, [2]Assignment{swapped[0], swapped[1]}, actor)
+	if err != nil {
+		return nil, err
+	}
+	published := make([]events.Event, 0, len(restored))
+	for _, as := range restored {
+		published = append(published, events.NewEvent(events.AssignmentSwapped, as.ChatID, as))
+	}
+	return published, nil
+}
+
+// Revert every assignment change made by change ID in one transaction.
+// Deleted assignments are restored with their original ID and creation time.
+// Returns ErrNotUndone if schedule was changed after.
+func (asr *AssignmentRepoData) UndoChange(ctx context.Context, change uuid.UUID, actor Actor) ([]Assignment, error) {
+	changes, err := asr.GetAssignmentEvents(ctx, EventFilter{ChangeID: change})
 	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, ErrNotUndone
+	}
+
+	undo := uuid.New()
+	published := make([]events.Event, 0, len(changes))
+	err = pgx.BeginFunc(ctx, asr.conn, func(tx pgx.Tx) error {
+		// Restored assignments take slots, so takers must wait
+		if err := lockChatSlots(ctx, tx, changes[0].ChatID); err != nil {
+			return err
+		}
+		var swapped []Assignment
+		for _, event := range changes {
+			if event.Kind == EventSwap {
+				as, err := getUnchangedAssignment(ctx, tx, event)
+				if err != nil {
+					return err
+				}
+				swapped = append(swapped, as)
+				continue
+			}
+
+			var undone events.Event
+			var err error
+			switch event.Kind {
+			case EventCreate:
+				undone, err = undoCreate(ctx, tx, undo, event, actor)
+			case EventDelete:
+				undone, err = undoDelete(ctx, tx, undo, event, actor)
+			default:
+				return ErrNotUndone
+			}
+			if err != nil {
+				return err
+			}
+			published = append(published, undone)
+		}
+
+		restored, err := undoSwap(ctx, tx, undo, swapped, actor)
+		published = append(published, restored...)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	if !result.Delete() {
-		return ErrNotDeleted
+
+	result := make([]Assignment, 0, len(published))
+	for _, event := range published {
+		events.Publish(event)
+		result = append(result, event.Data.(Assignment))
 	}
-	return nil
+	return result, nil
+}
+
+// Return audit log records matching filter, latest first
+func (asr *AssignmentRepoData) GetAssignmentEvents(
+	ctx context.Context,
+	filter EventFilter,
+) ([]AssignmentEvent, error) {
+	query := goqu.From(assignmentEventsTableName).
+		Select(AssignmentEvent{}).
+		Order(goqu.I("created_at").Desc())
+
+	if filter.ChatID != 0 {
+		query = query.Where(goqu.C("chat_id").Eq(filter.ChatID))
+	}
+	if filter.ChangeID != uuid.Nil {
+		query = query.Where(goqu.C("change_uuid").Eq(filter.ChangeID.String()))
+	}
+	if !filter.From.IsZero() {
+		query = query.Where(goqu.C("at").Gte(filter.From.Format(utils.DateFormat)))
+	}
+	if !filter.To.IsZero() {
+		query = query.Where(goqu.C("at").Lte(filter.To.Format(utils.DateFormat)))
+	}
+	if filter.Kind != "" {
+		query = query.Where(goqu.C("kind").Eq(filter.Kind))
+	}
+	if filter.Limit != 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	sql, params, err := query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []AssignmentEvent{}, err
+	}
+	defer rows.Close()
+
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[AssignmentEvent])
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []AssignmentEvent{}, err
+	}
+	return result, nil
 }
 
 // Return schedule due specified date and for specified chat
@@ -78,6 +682,9 @@ func (asr *AssignmentRepoData) GetSchedule(
 	}
 	assignmentsMap := make(map[time.Time]Assignment)
 	for _, assignment := range assignments {
+		if assignment.IsBackup() {
+			continue
+		}
 		assignmentsMap[utils.GetDate(assignment.At)] = assignment
 	}
 
@@ -141,6 +748,76 @@ func (asr *AssignmentRepoData) GetAssignmentSchedule(
 	return as, nil
 }
 
+// Return assignments of the chat between from and to dates inclusive.
+// Unlike GetAssignmentSchedule past dates are included.
+func (asr *AssignmentRepoData) GetAssignmentsInRange(
+	ctx context.Context,
+	from time.Time,
+	to time.Time,
+	chatID int64,
+) ([]Assignment, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select(Assignment{}).
+		Where(goqu.And(
+			goqu.C("chat_id").Eq(chatID),
+			goqu.C("at").
+				Between(
+					exp.NewRangeVal(
+						from.Format(utils.DateFormat),
+						to.Format(utils.DateFormat))),
+		)).
+		Order(goqu.I("at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Assignment{}, err
+	}
+	defer rows.Close()
+
+	as, err := pgx.CollectRows(rows, pgx.RowToStructByName[Assignment])
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Assignment{}, err
+	}
+	return as, nil
+}
+
+// Assignments of chat matching filter ordered by time
+func (asr *AssignmentRepoData) GetAssignments(
+	ctx context.Context,
+	filter ResetFilter,
+) ([]Assignment, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select(Assignment{}).
+		Where(filter.where()...).
+		Order(goqu.I("at").Asc(), goqu.I("starts_at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Assignment{}, err
+	}
+	defer rows.Close()
+
+	as, err := pgx.CollectRows(rows, pgx.RowToStructByName[Assignment])
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Assignment{}, err
+	}
+	return as, nil
+}
+
 // Get assignments for all chats due specified date
 func (asr *AssignmentRepoData) GetAssignmentScheduleAllChats(
 	ctx context.Context,
@@ -187,18 +864,89 @@ func (asr *AssignmentRepoData) GetAssignmentByDate(
 	ctx context.Context,
 	date time.Time,
 	chatID int64,
+	role string,
 ) (Assignment, error) {
 	sql, params, err := goqu.From(assignmentsTableName).
 		Select(Assignment{}).
 		Where(goqu.Ex{
 			"at":      utils.GetDate(date).Format(utils.DateFormat),
 			"chat_id": chatID,
+			"role":    role,
 		}).
+		// First shift if day is split
+		Order(goqu.I("starts_at").Asc()).
+		Limit(1).
+		ToSQL()
+	logger.Log.Debug().Str("sql", sql).Send()
+	if err != nil {
+		return Assignment{}, err
+	}
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return Assignment{}, err
+	}
+
+	as, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Assignment])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return Assignment{}, nil
+	case err != nil:
+		return Assignment{}, err
+	default:
+	}
+	return as, nil
+}
+
+func (asr *AssignmentRepoData) GetAssignment(ctx context.Context, uid uuid.UUID) (Assignment, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select(Assignment{}).
+		Where(goqu.Ex{"uuid": uid.String()}).
 		ToSQL()
+	if err != nil {
+		return Assignment{}, err
+	}
 	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return Assignment{}, err
+	}
+
+	as, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Assignment])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return Assignment{}, ErrNotFound
+	case err != nil:
+		return Assignment{}, err
+	default:
+	}
+	return as, nil
+}
+
+// Return assignment of given role covering given moment.
+// Empty assignment is returned if nobody is on duty.
+func (asr *AssignmentRepoData) GetAssignmentAt(
+	ctx context.Context,
+	moment time.Time,
+	chatID int64,
+	role string,
+) (Assignment, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select(Assignment{}).
+		Where(
+			goqu.C("chat_id").Eq(chatID),
+			goqu.C("role").Eq(role),
+			goqu.C("starts_at").Lte(moment),
+			goqu.C("ends_at").Gt(moment),
+		).
+		Order(goqu.I("starts_at").Desc()).
+		Limit(1).
+		ToSQL()
 	if err != nil {
 		return Assignment{}, err
 	}
+	logger.Log.Debug().Str("sql", sql).Send()
 
 	rows, err := asr.conn.Query(ctx, sql, params...)
 	if err != nil {
@@ -216,15 +964,133 @@ func (asr *AssignmentRepoData) GetAssignmentByDate(
 	return as, nil
 }
 
+// Return shift assignments of all chats starting after from and up to to
+func (asr *AssignmentRepoData) GetShiftsStartingBetween(
+	ctx context.Context,
+	from time.Time,
+	to time.Time,
+) ([]Assignment, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select(Assignment{}).
+		Where(
+			goqu.C("starts_at").Gt(from),
+			goqu.C("starts_at").Lte(to),
+		).
+		Order(goqu.I("starts_at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all, err := pgx.CollectRows(rows, pgx.RowToStructByName[Assignment])
+	if err != nil {
+		return nil, err
+	}
+	shifts := make([]Assignment, 0, len(all))
+	for _, as := range all {
+		if as.IsShift() {
+			shifts = append(shifts, as)
+		}
+	}
+	return shifts, nil
+}
+
+// Return assignments of all chats covering given moment
+func (asr *AssignmentRepoData) GetAssignmentsAtAllChats(
+	ctx context.Context,
+	moment time.Time,
+) ([]Assignment, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select(Assignment{}).
+		Where(
+			goqu.C("starts_at").Lte(moment),
+			goqu.C("ends_at").Gt(moment),
+		).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Assignment])
+}
+
+// Return duties of operator in every chat starting from specified date
+func (asr *AssignmentRepoData) GetOperatorAssignments(
+	ctx context.Context,
+	userID int64,
+	from time.Time,
+) ([]Assignment, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select(Assignment{}).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("at").Gte(from.Format(utils.DateFormat)),
+		).
+		Order(goqu.I("at").Asc(), goqu.I("chat_id").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Assignment{}, err
+	}
+	defer rows.Close()
+
+	as, err := pgx.CollectRows(rows, pgx.RowToStructByName[Assignment])
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Assignment{}, err
+	}
+	return as, nil
+}
+
+// Dates of every slot from today until due: working days,
+// or mondays of weeks with working days if weekly.
+// Returns start of the first slot too.
+func getSlotDates(due time.Time, weekly bool) (time.Time, calendar.TimeSet, error) {
+	today := utils.GetToday()
+	if weekly {
+		today = utils.GetStartOfWeek(today)
+	}
+	dates, err := calendar.GetWorkingDays(today, due)
+	if err != nil || !weekly {
+		return today, dates, err
+	}
+	weeks := calendar.TimeSet{}
+	for date := range dates {
+		weeks.Add(utils.GetStartOfWeek(date))
+	}
+	return today, weeks, nil
+}
+
 // Return free duty slots for
-// specified number of weeks
+// specified number of weeks.
+// Weekly slots are mondays of weeks with working days.
 func (asr *AssignmentRepoData) GetFreeSlots(
 	ctx context.Context,
 	due time.Time,
 	chatID int64,
+	weekly bool,
 ) ([]time.Time, error) {
-	today := utils.GetToday()
-	dates, err := calendar.GetWorkingDays(today, due)
+	today, dates, err := getSlotDates(due, weekly)
 	if err != nil {
 		return []time.Time{}, err
 	}
@@ -232,7 +1098,7 @@ func (asr *AssignmentRepoData) GetFreeSlots(
 	sql, params, err := goqu.From(assignmentsTableName).
 		Select("at").
 		Where(goqu.And(
-			goqu.Ex{"chat_id": chatID},
+			goqu.Ex{"chat_id": chatID, "role": RolePrimary},
 			goqu.I("at").Between(
 				exp.NewRangeVal(
 					today.Format(utils.DateFormat),
@@ -311,6 +1177,58 @@ func (asr AssignmentRepoData) GetAllChats(ctx context.Context) ([]int64, error)
 	return chats, nil
 }
 
+// Return chats where user was ever assigned
+func (asr *AssignmentRepoData) GetOperatorChats(ctx context.Context, userID int64) ([]int64, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select("chat_id").
+		Distinct().
+		Where(goqu.C("user_id").Eq(userID)).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// Return primary operators assigned in chat on or after since date
+// with their latest names
+func (asr *AssignmentRepoData) GetChatOperators(
+	ctx context.Context,
+	chatID int64,
+	since time.Time,
+) ([]Operator, error) {
+	sql, params, err := goqu.From(assignmentsTableName).
+		Select("user_id", "operator").
+		Distinct("user_id").
+		Where(
+			goqu.C("chat_id").Eq(chatID),
+			goqu.C("role").Eq(RolePrimary),
+			goqu.C("at").Gte(since.Format(utils.DateFormat)),
+		).
+		Order(goqu.I("user_id").Asc(), goqu.I("at").Desc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := asr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Operator])
+}
+
 func (asr AssignmentRepoData) Close() error {
 	asr.conn.Close()
 	return nil
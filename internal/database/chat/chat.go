@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	chatSettingsTableName = "chat_settings"
+	keyboardsTableName    = "keyboards"
+)
+
+// Views of schedule keyboard
+const (
+	KeyboardWeek  = "week"
+	KeyboardMonth = "month"
+)
+
+// How long single duty lasts
+const (
+	DutyPeriodDay  = "day"
+	DutyPeriodWeek = "week"
+)
+
+// Table that has chat_id column and should follow
+// the chat when its ID changes
+type perChatTable struct {
+	Name string
+	// Columns unique along with chat_id
+	Key []string
+}
+
+var perChatTables = []perChatTable{
+	{Name: "assignments", Key: []string{"user_id", "starts_at"}},
+	{Name: "assignment_events"},
+	{Name: "webhooks", Key: []string{"url"}},
+	{Name: "alert_routes"},
+	{Name: "incidents"},
+	// Whole table row is unique per chat
+	{Name: chatSettingsTableName, Key: []string{}},
+	{Name: "shift_templates", Key: []string{"position"}},
+	{Name: "operator_preferences", Key: []string{"user_id"}},
+	{Name: "recurrences"},
+	{Name: "watches", Key: []string{"user_id", "at"}},
+	{Name: "redemptions"},
+	{Name: "pending_actions"},
+}
+
+type ChatRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var ChatRepo ChatRepoer
+
+type ChatRepoer interface {
+	MigrateChat(ctx context.Context, from, to int64) (int64, error)
+	GetSettings(ctx context.Context, chatID int64) (Settings, error)
+	// Chats that opted in to monthly reports
+	GetMonthlyReportChats(ctx context.Context) ([]int64, error)
+	SaveSettings(ctx context.Context, settings Settings) error
+	AddKeyboard(ctx context.Context, keyboard Keyboard) error
+	SetKeyboardShownFrom(ctx context.Context, chatID int64, messageID int, shownFrom time.Time) error
+	DeleteKeyboard(ctx context.Context, chatID int64, messageID int) error
+	GetKeyboards(ctx context.Context, chatID int64) ([]Keyboard, error)
+}
+
+// Per chat bot configuration
+type Settings struct {
+	ChatID int64 `db:"chat_id"`
+	// Forum topic for announcements and warnings. 0 means General topic
+	AnnounceThreadID int `db:"announce_thread_id"`
+	// One of DutyPeriodDay or DutyPeriodWeek
+	DutyPeriod string `db:"duty_period"`
+	// Ask for confirmation before resetting duty of somebody else
+	ConfirmReset bool `db:"confirm_reset"`
+	// Send report for previous month every month
+	MonthlyReport bool `db:"monthly_report"`
+	// Admin receiving monthly report in private chat. 0 means the chat itself
+	ReportUserID int64 `db:"report_user_id"`
+	// When settings were changed
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Schedule keyboard message open in chat
+type Keyboard struct {
+	ChatID    int64 `db:"chat_id"`
+	MessageID int   `db:"message_id"`
+	// One of KeyboardWeek or KeyboardMonth
+	View string `db:"view"`
+	// First day of shown week or month
+	ShownFrom time.Time `db:"shown_from"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Settings used for chats that never changed them
+func DefaultSettings(chatID int64) Settings {
+	return Settings{ChatID: chatID, DutyPeriod: DutyPeriodDay}
+}
+
+func InitChatRepo(ctx context.Context, dsn string) (ChatRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &ChatRepoData{conn: conn}
+	ChatRepo = result
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// New chat may already have rows, e.g. when somebody used the bot
+// in supergroup before migration message was processed
+func TestDropConflicts(t *testing.T) {
+	sql, _, err := dropConflicts(perChatTable{Name: "watches", Key: []string{"user_id", "at"}}, 1, 2).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		`DELETE FROM "watches" WHERE (("chat_id" = 1) AND EXISTS (SELECT 1 FROM "watches" AS "target" `+
+			`WHERE (("target"."chat_id" = 2) AND ("target"."user_id" = "watches"."user_id") `+
+			`AND ("target"."at" = "watches"."at"))))`,
+		sql,
+	)
+}
+
+func TestDropConflictsWholeRow(t *testing.T) {
+	sql, _, err := dropConflicts(perChatTable{Name: chatSettingsTableName, Key: []string{}}, 1, 2).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		`DELETE FROM "chat_settings" WHERE (("chat_id" = 1) AND EXISTS `+
+			`(SELECT 1 FROM "chat_settings" AS "target" WHERE ("target"."chat_id" = 2)))`,
+		sql,
+	)
+}
+
+// Every unique constraint with chat_id must be listed,
+// otherwise migration fails when new chat has data
+func TestPerChatTablesKeys(t *testing.T) {
+	unique := map[string]bool{
+		"assignments":          true,
+		"webhooks":             true,
+		"chat_settings":        true,
+		"shift_templates":      true,
+		"operator_preferences": true,
+		"watches":              true,
+	}
+	for _, table := range perChatTables {
+		assert.Equal(t, unique[table.Name], table.Key != nil, table.Name)
+	}
+}
+
+// Chats get monthly report only after admins turn it on
+func TestMonthlyReportChats(t *testing.T) {
+	sql, _, err := monthlyReportChats().ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT "chat_id" FROM "chat_settings" WHERE ("monthly_report" IS TRUE)`, sql)
+
+	assert.False(t, DefaultSettings(1).MonthlyReport)
+}
@@ -0,0 +1,222 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ ChatRepoer = &ChatRepoData{}
+
+// Rows of old chat clashing with rows new chat already has.
+// New chat rows are newer, so they win.
+func dropConflicts(table perChatTable, from, to int64) *goqu.DeleteDataset {
+	const target = "target"
+	same := []exp.Expression{goqu.I(target + ".chat_id").Eq(to)}
+	for _, column := range table.Key {
+		same = append(same, goqu.I(target+"."+column).Eq(goqu.I(table.Name+"."+column)))
+	}
+	return goqu.Delete(table.Name).Where(
+		goqu.C("chat_id").Eq(from),
+		goqu.L("EXISTS ?", goqu.From(goqu.T(table.Name).As(target)).
+			Select(goqu.L("1")).
+			Where(same...)),
+	)
+}
+
+func moveRows(table perChatTable, from, to int64) *goqu.UpdateDataset {
+	return goqu.Update(table.Name).
+		Set(goqu.Record{"chat_id": to}).
+		Where(goqu.Ex{"chat_id": from})
+}
+
+// Move all chat data to new chat ID in single transaction.
+// Rows conflicting with data new chat already has are dropped.
+// Returns number of moved rows. Migrating already migrated chat is no-op.
+func (cr *ChatRepoData) MigrateChat(ctx context.Context, from, to int64) (int64, error) {
+	var moved int64
+	err := pgx.BeginFunc(ctx, cr.conn, func(tx pgx.Tx) error {
+		for _, table := range perChatTables {
+			if table.Key != nil {
+				sql, params, err := dropConflicts(table, from, to).ToSQL()
+				if err != nil {
+					return err
+				}
+				logger.Log.Debug().Str("sql", sql).Send()
+
+				_, err = tx.Exec(ctx, sql, params...)
+				if err != nil {
+					return err
+				}
+			}
+
+			sql, params, err := moveRows(table, from, to).ToSQL()
+			if err != nil {
+				return err
+			}
+			logger.Log.Debug().Str("sql", sql).Send()
+
+			result, err := tx.Exec(ctx, sql, params...)
+			if err != nil {
+				return err
+			}
+			moved += result.RowsAffected()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+// Return chat settings or defaults if chat has none
+func (cr *ChatRepoData) GetSettings(ctx context.Context, chatID int64) (Settings, error) {
+	sql, params, err := goqu.From(chatSettingsTableName).
+		Select(Settings{}).
+		Where(goqu.Ex{"chat_id": chatID}).
+		ToSQL()
+	if err != nil {
+		return Settings{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := cr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Settings])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return DefaultSettings(chatID), nil
+	case err != nil:
+		return Settings{}, err
+	default:
+	}
+	return settings, nil
+}
+
+// Chats that opted in to monthly report
+func monthlyReportChats() *goqu.SelectDataset {
+	return goqu.From(chatSettingsTableName).
+		Select("chat_id").
+		Where(goqu.Ex{"monthly_report": true})
+}
+
+func (cr *ChatRepoData) GetMonthlyReportChats(ctx context.Context) ([]int64, error) {
+	sql, params, err := monthlyReportChats().ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := cr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+func (cr *ChatRepoData) SaveSettings(ctx context.Context, settings Settings) error {
+	settings.UpdatedAt = time.Now().UTC()
+	sql, params, err := goqu.Insert(chatSettingsTableName).
+		Rows(settings).
+		OnConflict(goqu.DoUpdate("chat_id", settings)).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = cr.conn.Exec(ctx, sql, params...)
+	return err
+}
+
+func (cr *ChatRepoData) AddKeyboard(ctx context.Context, keyboard Keyboard) error {
+	sql, params, err := goqu.Insert(keyboardsTableName).
+		Rows(keyboard).
+		OnConflict(goqu.DoUpdate("chat_id, message_id", keyboard)).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = cr.conn.Exec(ctx, sql, params...)
+	return err
+}
+
+// Remember which week or month keyboard shows after navigation
+func (cr *ChatRepoData) SetKeyboardShownFrom(
+	ctx context.Context,
+	chatID int64,
+	messageID int,
+	shownFrom time.Time,
+) error {
+	sql, params, err := goqu.Update(keyboardsTableName).
+		Set(goqu.Record{"shown_from": shownFrom.Format(utils.DateFormat)}).
+		Where(goqu.Ex{
+			"chat_id":    chatID,
+			"message_id": messageID,
+		}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = cr.conn.Exec(ctx, sql, params...)
+	return err
+}
+
+func (cr *ChatRepoData) DeleteKeyboard(ctx context.Context, chatID int64, messageID int) error {
+	sql, params, err := goqu.Delete(keyboardsTableName).
+		Where(goqu.Ex{
+			"chat_id":    chatID,
+			"message_id": messageID,
+		}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = cr.conn.Exec(ctx, sql, params...)
+	return err
+}
+
+func (cr *ChatRepoData) GetKeyboards(ctx context.Context, chatID int64) ([]Keyboard, error) {
+	sql, params, err := goqu.From(keyboardsTableName).
+		Select(Keyboard{}).
+		Where(goqu.Ex{"chat_id": chatID}).
+		Order(goqu.I("created_at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := cr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Keyboard])
+}
+
+func (cr ChatRepoData) Close() error {
+	cr.conn.Close()
+	return nil
+}
@@ -0,0 +1,45 @@
+package checkpoint
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const checkpointsTableName = "task_checkpoints"
+
+// Names of tasks keeping checkpoints
+const (
+	ShiftAnnounce = "shift_announce"
+)
+
+type CheckpointRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var CheckpointRepo CheckpointRepoer
+
+type CheckpointRepoer interface {
+	// Moment task has processed everything up to.
+	// Zero time if task never saved checkpoint.
+	GetCheckpoint(ctx context.Context, task string) (time.Time, error)
+	SetCheckpoint(ctx context.Context, task string, checkedAt time.Time) error
+}
+
+// Progress of periodic task, so it continues
+// from where it stopped after restart
+type Checkpoint struct {
+	Task      string    `db:"task"`
+	CheckedAt time.Time `db:"checked_at"`
+}
+
+func InitCheckpointRepo(ctx context.Context, dsn string) (CheckpointRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &CheckpointRepoData{conn: conn}
+	CheckpointRepo = result
+	return result, nil
+}
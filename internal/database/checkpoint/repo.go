@@ -0,0 +1,56 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ CheckpointRepoer = &CheckpointRepoData{}
+
+func (cr *CheckpointRepoData) GetCheckpoint(ctx context.Context, task string) (time.Time, error) {
+	sql, params, err := goqu.From(checkpointsTableName).
+		Select(Checkpoint{}).
+		Where(goqu.Ex{"task": task}).
+		ToSQL()
+	if err != nil {
+		return time.Time{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := cr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	c, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Checkpoint])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return time.Time{}, nil
+	case err != nil:
+		return time.Time{}, err
+	}
+	return c.CheckedAt, nil
+}
+
+func (cr *CheckpointRepoData) SetCheckpoint(ctx context.Context, task string, checkedAt time.Time) error {
+	sql, params, err := goqu.Insert(checkpointsTableName).
+		Rows(Checkpoint{Task: task, CheckedAt: checkedAt}).
+		OnConflict(goqu.DoUpdate("task", goqu.Record{"checked_at": checkedAt})).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = cr.conn.Exec(ctx, sql, params...)
+	return err
+}
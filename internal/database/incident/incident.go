@@ -0,0 +1,76 @@
+package incident
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const incidentsTableName = "incidents"
+
+// Incident statuses
+const (
+	StatusOpen         = "open"
+	StatusAcknowledged = "acknowledged"
+	StatusResolved     = "resolved"
+)
+
+type IncidentRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var IncidentRepo IncidentRepoer
+
+type IncidentRepoer interface {
+	AddIncident(ctx context.Context, inc Incident) error
+	GetIncident(ctx context.Context, id uuid.UUID) (Incident, error)
+	GetIncidentByGroupKey(ctx context.Context, chatID int64, groupKey string) (Incident, error)
+	GetActiveIncidents(ctx context.Context, chatID int64) ([]Incident, error)
+	GetUnacknowledgedIncidents(ctx context.Context, escalatedBefore time.Time) ([]Incident, error)
+	Acknowledge(ctx context.Context, id uuid.UUID, by string) (bool, error)
+	Resolve(ctx context.Context, id uuid.UUID, by string) (bool, error)
+	// Operator ID is 0 if incident is escalated to several people, e.g. admins
+	Escalate(ctx context.Context, id uuid.UUID, level int, operator string, operatorID int64) error
+}
+
+type Incident struct {
+	ID uuid.UUID `db:"uuid"`
+	// Chat where incident was posted
+	ChatID int64 `db:"chat_id"`
+	// Message with incident buttons
+	MessageID int `db:"message_id"`
+	// Alertmanager group key to match resolve notifications
+	GroupKey string `db:"group_key"`
+	// Short description
+	Title string `db:"title"`
+	// One of open, acknowledged or resolved
+	Status string `db:"status"`
+	// Who is expected to acknowledge incident now
+	Operator string `db:"operator"`
+	// Telegram ID of the operator, 0 if nobody or several people are
+	OperatorID int64 `db:"operator_id"`
+	// How many times incident was escalated
+	EscalationLevel int `db:"escalation_level"`
+	// When incident was created
+	CreatedAt time.Time `db:"created_at"`
+	// When incident was created or last escalated
+	EscalatedAt time.Time `db:"escalated_at"`
+	// Who and when acknowledged incident
+	AcknowledgedBy string     `db:"acknowledged_by"`
+	AcknowledgedAt *time.Time `db:"acknowledged_at"`
+	// Who and when resolved incident
+	ResolvedBy string     `db:"resolved_by"`
+	ResolvedAt *time.Time `db:"resolved_at"`
+}
+
+func InitIncidentRepo(ctx context.Context, dsn string) (IncidentRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &IncidentRepoData{conn: conn}
+	IncidentRepo = result
+	return result, nil
+}
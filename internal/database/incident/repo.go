@@ -0,0 +1,203 @@
+package incident
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ IncidentRepoer = &IncidentRepoData{}
+
+// errors
+var (
+	ErrNotInserted = errors.New("pgx CommandTag is not INSERT")
+	ErrNotFound    = errors.New("incident not found")
+)
+
+func (ir *IncidentRepoData) AddIncident(ctx context.Context, inc Incident) error {
+	sql, params, err := goqu.Insert(incidentsTableName).Rows(inc).ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := ir.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Insert() {
+		return ErrNotInserted
+	}
+	return nil
+}
+
+func (ir *IncidentRepoData) getOne(ctx context.Context, where goqu.Ex) (Incident, error) {
+	sql, params, err := goqu.From(incidentsTableName).
+		Select(Incident{}).
+		Where(where).
+		Order(goqu.I("created_at").Desc()).
+		Limit(1).
+		ToSQL()
+	if err != nil {
+		return Incident{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := ir.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return Incident{}, err
+	}
+
+	inc, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Incident])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return Incident{}, ErrNotFound
+	case err != nil:
+		return Incident{}, err
+	default:
+	}
+	return inc, nil
+}
+
+func (ir *IncidentRepoData) getMany(ctx context.Context, where goqu.Expression) ([]Incident, error) {
+	sql, params, err := goqu.From(incidentsTableName).
+		Select(Incident{}).
+		Where(where).
+		Order(goqu.I("created_at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := ir.conn.Query(ctx, sql, params...)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Incident{}, err
+	}
+	defer rows.Close()
+
+	incidents, err := pgx.CollectRows(rows, pgx.RowToStructByName[Incident])
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Incident{}, err
+	}
+	return incidents, nil
+}
+
+func (ir *IncidentRepoData) GetIncident(ctx context.Context, id uuid.UUID) (Incident, error) {
+	return ir.getOne(ctx, goqu.Ex{"uuid": id.String()})
+}
+
+// Return latest not resolved incident created for alertmanager group
+func (ir *IncidentRepoData) GetIncidentByGroupKey(
+	ctx context.Context,
+	chatID int64,
+	groupKey string,
+) (Incident, error) {
+	return ir.getOne(ctx, goqu.Ex{
+		"chat_id":   chatID,
+		"group_key": groupKey,
+		"status":    goqu.Op{"neq": StatusResolved},
+	})
+}
+
+// Return open and acknowledged incidents of the chat
+func (ir *IncidentRepoData) GetActiveIncidents(ctx context.Context, chatID int64) ([]Incident, error) {
+	return ir.getMany(ctx, goqu.Ex{
+		"chat_id": chatID,
+		"status":  goqu.Op{"neq": StatusResolved},
+	})
+}
+
+// Return incidents of all chats that nobody acknowledged
+// since their creation or last escalation before specified time
+func (ir *IncidentRepoData) GetUnacknowledgedIncidents(
+	ctx context.Context,
+	escalatedBefore time.Time,
+) ([]Incident, error) {
+	return ir.getMany(ctx, goqu.And(
+		goqu.C("status").Eq(StatusOpen),
+		goqu.C("escalated_at").Lte(escalatedBefore),
+	))
+}
+
+func (ir *IncidentRepoData) update(ctx context.Context, where goqu.Ex, record goqu.Record) (bool, error) {
+	sql, params, err := goqu.Update(incidentsTableName).
+		Set(record).
+		Where(where).
+		ToSQL()
+	if err != nil {
+		return false, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := ir.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// Mark open incident as acknowledged.
+// Returns false if incident was already acknowledged or resolved.
+func (ir *IncidentRepoData) Acknowledge(ctx context.Context, id uuid.UUID, by string) (bool, error) {
+	return ir.update(
+		ctx,
+		goqu.Ex{"uuid": id.String(), "status": StatusOpen},
+		goqu.Record{
+			"status":          StatusAcknowledged,
+			"acknowledged_by": by,
+			"acknowledged_at": time.Now().UTC(),
+		},
+	)
+}
+
+// Mark incident as resolved.
+// Returns false if incident was already resolved.
+func (ir *IncidentRepoData) Resolve(ctx context.Context, id uuid.UUID, by string) (bool, error) {
+	return ir.update(
+		ctx,
+		goqu.Ex{"uuid": id.String(), "status": goqu.Op{"neq": StatusResolved}},
+		goqu.Record{
+			"status":      StatusResolved,
+			"resolved_by": by,
+			"resolved_at": time.Now().UTC(),
+		},
+	)
+}
+
+// Pass open incident to another operator
+func (ir *IncidentRepoData) Escalate(
+	ctx context.Context,
+	id uuid.UUID,
+	level int,
+	operator string,
+	operatorID int64,
+) error {
+	_, err := ir.update(
+		ctx,
+		goqu.Ex{"uuid": id.String(), "status": StatusOpen},
+		goqu.Record{
+			"escalation_level": level,
+			"operator":         operator,
+			"operator_id":      operatorID,
+			"escalated_at":     time.Now().UTC(),
+		},
+	)
+	return err
+}
+
+func (ir IncidentRepoData) Close() error {
+	ir.conn.Close()
+	return nil
+}
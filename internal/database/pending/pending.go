@@ -0,0 +1,86 @@
+package pending
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const pendingTableName = "pending_actions"
+
+// Kinds of pending actions
+const (
+	// Operator asked another one to swap duties
+	KindSwap = "swap"
+	// Admin previewed duties to reset
+	KindReset = "reset"
+)
+
+type PendingRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var PendingRepo PendingRepoer
+
+type PendingRepoer interface {
+	AddPending(ctx context.Context, p Pending) error
+	// Not expired action, ErrNotFound otherwise
+	GetPending(ctx context.Context, id uuid.UUID) (Pending, error)
+	// Remove not expired action and return it, so it is done once.
+	// ErrNotFound is returned for missing and expired actions.
+	TakePending(ctx context.Context, id uuid.UUID) (Pending, error)
+	DeletePending(ctx context.Context, id uuid.UUID) error
+}
+
+// Action waiting for confirmation. Callback data is too small
+// for its details, so buttons carry only its ID.
+type Pending struct {
+	ID     uuid.UUID `db:"uuid"`
+	ChatID int64     `db:"chat_id"`
+	// Who asked for the action
+	UserID int64 `db:"user_id"`
+	// User the action is about (e.g. whose duties are reset), 0 if none
+	SubjectID int64 `db:"subject_id"`
+	// One of KindSwap or KindReset
+	Kind string `db:"kind"`
+	// JSON list of assignment IDs action applies to
+	Assignments []byte    `db:"assignments"`
+	ExpiresAt   time.Time `db:"expires_at"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+func NewPending(chatID, userID int64, kind string, ids []uuid.UUID, ttl time.Duration) (Pending, error) {
+	assignments, err := json.Marshal(ids)
+	if err != nil {
+		return Pending{}, err
+	}
+	now := time.Now().UTC()
+	return Pending{
+		ID:          uuid.New(),
+		ChatID:      chatID,
+		UserID:      userID,
+		Kind:        kind,
+		Assignments: assignments,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+	}, nil
+}
+
+func (p Pending) AssignmentIDs() ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := json.Unmarshal(p.Assignments, &ids)
+	return ids, err
+}
+
+func InitPendingRepo(ctx context.Context, dsn string) (PendingRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &PendingRepoData{conn: conn}
+	PendingRepo = result
+	return result, nil
+}
@@ -0,0 +1,99 @@
+package pending
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/google/uuid"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotInserted = errors.New("pgx CommandTag is not INSERT")
+	ErrNotFound    = errors.New("pending action not found")
+)
+
+var _ PendingRepoer = &PendingRepoData{}
+
+func (pr *PendingRepoData) AddPending(ctx context.Context, p Pending) error {
+	sql, params, err := goqu.Insert(pendingTableName).Rows(p).ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := pr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Insert() {
+		return ErrNotInserted
+	}
+	return nil
+}
+
+func (pr *PendingRepoData) GetPending(ctx context.Context, id uuid.UUID) (Pending, error) {
+	return pr.queryOne(ctx, goqu.From(pendingTableName).
+		Select(Pending{}).
+		Where(
+			goqu.C("uuid").Eq(id.String()),
+			goqu.C("expires_at").Gt(time.Now().UTC()),
+		))
+}
+
+func (pr *PendingRepoData) TakePending(ctx context.Context, id uuid.UUID) (Pending, error) {
+	return pr.queryOne(ctx, goqu.Delete(pendingTableName).
+		Where(
+			goqu.C("uuid").Eq(id.String()),
+			goqu.C("expires_at").Gt(time.Now().UTC()),
+		).
+		Returning(goqu.Star()))
+}
+
+func (pr *PendingRepoData) queryOne(ctx context.Context, query exp.SQLExpression) (Pending, error) {
+	sql, params, err := query.ToSQL()
+	if err != nil {
+		return Pending{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := pr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return Pending{}, err
+	}
+
+	p, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Pending])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return Pending{}, ErrNotFound
+	case err != nil:
+		return Pending{}, err
+	default:
+	}
+	return p, nil
+}
+
+// Drop action and every expired one
+func (pr *PendingRepoData) DeletePending(ctx context.Context, id uuid.UUID) error {
+	sql, params, err := goqu.Delete(pendingTableName).
+		Where(goqu.Or(
+			goqu.C("uuid").Eq(id.String()),
+			goqu.C("expires_at").Lte(time.Now().UTC()),
+		)).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = pr.conn.Exec(ctx, sql, params...)
+	return err
+}
@@ -0,0 +1,71 @@
+package preference
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const preferencesTableName = "operator_preferences"
+
+type PreferenceRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var PreferenceRepo PreferenceRepoer
+
+type PreferenceRepoer interface {
+	GetPreferences(ctx context.Context, chatID int64) ([]Preference, error)
+	// Return preference of operator or defaults if operator has none
+	GetPreference(ctx context.Context, chatID, userID int64) (Preference, error)
+	SavePreference(ctx context.Context, pref Preference) error
+}
+
+// Scheduling wishes of operator in chat
+type Preference struct {
+	ChatID int64 `db:"chat_id"`
+	UserID int64 `db:"user_id"`
+	// Bit per time.Weekday operator avoids
+	AvoidWeekdays int `db:"avoid_weekdays"`
+	// Duties per calendar month, 0 means no limit
+	MaxPerMonth int `db:"max_per_month"`
+	// No duties on two days in a row
+	NoConsecutive bool `db:"no_consecutive"`
+	// No duty on the day after a duty on holiday
+	NoAfterHoliday bool      `db:"no_after_holiday"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+// Preference used for operators that never changed it
+func DefaultPreference(chatID, userID int64) Preference {
+	return Preference{ChatID: chatID, UserID: userID}
+}
+
+// Avoided weekdays from Sunday to Saturday
+func (p Preference) Weekdays() []time.Weekday {
+	weekdays := make([]time.Weekday, 0)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if p.AvoidWeekdays&(1<<weekday) != 0 {
+			weekdays = append(weekdays, weekday)
+		}
+	}
+	return weekdays
+}
+
+func (p *Preference) SetWeekdays(weekdays []time.Weekday) {
+	p.AvoidWeekdays = 0
+	for _, weekday := range weekdays {
+		p.AvoidWeekdays |= 1 << weekday
+	}
+}
+
+func InitPreferenceRepo(ctx context.Context, dsn string) (PreferenceRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &PreferenceRepoData{conn: conn}
+	PreferenceRepo = result
+	return result, nil
+}
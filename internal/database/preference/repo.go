@@ -0,0 +1,77 @@
+package preference
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ PreferenceRepoer = &PreferenceRepoData{}
+
+func (pr *PreferenceRepoData) GetPreferences(ctx context.Context, chatID int64) ([]Preference, error) {
+	sql, params, err := goqu.From(preferencesTableName).
+		Select(Preference{}).
+		Where(goqu.Ex{"chat_id": chatID}).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := pr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Preference])
+}
+
+func (pr *PreferenceRepoData) GetPreference(ctx context.Context, chatID, userID int64) (Preference, error) {
+	sql, params, err := goqu.From(preferencesTableName).
+		Select(Preference{}).
+		Where(goqu.Ex{"chat_id": chatID, "user_id": userID}).
+		ToSQL()
+	if err != nil {
+		return Preference{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := pr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return Preference{}, err
+	}
+
+	pref, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Preference])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return DefaultPreference(chatID, userID), nil
+	case err != nil:
+		return Preference{}, err
+	default:
+	}
+	return pref, nil
+}
+
+func (pr *PreferenceRepoData) SavePreference(ctx context.Context, pref Preference) error {
+	pref.UpdatedAt = time.Now().UTC()
+	sql, params, err := goqu.Insert(preferencesTableName).
+		Rows(pref).
+		OnConflict(goqu.DoUpdate("chat_id, user_id", pref)).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = pr.conn.Exec(ctx, sql, params...)
+	return err
+}
@@ -0,0 +1,80 @@
+package recurrence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const recurrencesTableName = "recurrences"
+
+type RecurrenceRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var RecurrenceRepo RecurrenceRepoer
+
+type RecurrenceRepoer interface {
+	AddRecurrence(ctx context.Context, rule Recurrence) error
+	UpdateRecurrence(ctx context.Context, rule Recurrence) error
+	DeleteRecurrence(ctx context.Context, id uuid.UUID) error
+	// Rules of chat ordered by creation
+	GetRecurrences(ctx context.Context, chatID int64) ([]Recurrence, error)
+	// Rules of every chat that are not over before from date
+	GetActiveRecurrences(ctx context.Context, from time.Time) ([]Recurrence, error)
+	// Remember that rule days up to date were assigned or skipped
+	SetMaterializedUntil(ctx context.Context, id uuid.UUID, date time.Time) error
+}
+
+// Rule assigning operator to the same weekday every week
+type Recurrence struct {
+	ID       uuid.UUID    `db:"uuid"`
+	ChatID   int64        `db:"chat_id"`
+	UserID   int64        `db:"user_id"`
+	Operator string       `db:"operator"`
+	Weekday  time.Weekday `db:"weekday"`
+	// Role of made assignments
+	Role string `db:"role"`
+	// Last day of rule, nil means forever
+	Until *time.Time `db:"until"`
+	// Days up to this one were already assigned or skipped,
+	// so duties removed later don't come back. Nil if none were.
+	MaterializedUntil *time.Time `db:"materialized_until"`
+	CreatedAt         time.Time  `db:"created_at"`
+}
+
+// Days rule assigns between from and to inclusive
+func (r Recurrence) Dates(from, to time.Time) []time.Time {
+	if r.Until != nil && r.Until.Before(to) {
+		to = *r.Until
+	}
+
+	dates := make([]time.Time, 0)
+	shift := (int(r.Weekday) - int(from.Weekday()) + 7) % 7
+	for date := from.AddDate(0, 0, shift); !date.After(to); date = date.AddDate(0, 0, 7) {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// Days rule should assign from today to horizon inclusive,
+// without days already handled before
+func (r Recurrence) PendingDates(today, horizon time.Time) []time.Time {
+	from := today
+	if r.MaterializedUntil != nil && !r.MaterializedUntil.Before(from) {
+		from = r.MaterializedUntil.AddDate(0, 0, 1)
+	}
+	return r.Dates(from, horizon)
+}
+
+func InitRecurrenceRepo(ctx context.Context, dsn string) (RecurrenceRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &RecurrenceRepoData{conn: conn}
+	RecurrenceRepo = result
+	return result, nil
+}
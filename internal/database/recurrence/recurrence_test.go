@@ -0,0 +1,44 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func oct(d int) time.Time {
+	return time.Date(2026, time.October, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDates(t *testing.T) {
+	// October 19th 2026 is Monday
+	rule := Recurrence{Weekday: time.Wednesday}
+	assert.Equal(t, []time.Time{oct(21), oct(28)}, rule.Dates(oct(19), oct(31)))
+	assert.Equal(t, []time.Time{oct(21)}, rule.Dates(oct(21), oct(27)))
+	assert.Empty(t, rule.Dates(oct(22), oct(27)))
+
+	until := oct(27)
+	rule.Until = &until
+	assert.Equal(t, []time.Time{oct(21)}, rule.Dates(oct(19), oct(31)))
+
+	rule = Recurrence{Weekday: time.Sunday}
+	assert.Equal(t, []time.Time{oct(25)}, rule.Dates(oct(19), oct(31)))
+}
+
+func TestPendingDates(t *testing.T) {
+	rule := Recurrence{Weekday: time.Wednesday}
+	assert.Equal(t, []time.Time{oct(21), oct(28)}, rule.PendingDates(oct(19), oct(31)))
+
+	// Days handled before are not assigned again
+	materialized := oct(21)
+	rule.MaterializedUntil = &materialized
+	assert.Equal(t, []time.Time{oct(28)}, rule.PendingDates(oct(19), oct(31)))
+
+	materialized = oct(31)
+	assert.Empty(t, rule.PendingDates(oct(19), oct(31)))
+
+	// Old progress doesn't matter
+	materialized = oct(1)
+	assert.Equal(t, []time.Time{oct(21), oct(28)}, rule.PendingDates(oct(19), oct(31)))
+}
@@ -0,0 +1,141 @@
+package recurrence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/google/uuid"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotInserted = errors.New("pgx CommandTag is not INSERT")
+	ErrNotUpdated  = errors.New("pgx CommandTag is not UPDATE")
+	ErrNotDeleted  = errors.New("pgx CommandTag is not DELETE")
+)
+
+var _ RecurrenceRepoer = &RecurrenceRepoData{}
+
+func (rr *RecurrenceRepoData) AddRecurrence(ctx context.Context, rule Recurrence) error {
+	sql, params, err := goqu.Insert(recurrencesTableName).Rows(rule).ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := rr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Insert() {
+		return ErrNotInserted
+	}
+	return nil
+}
+
+func (rr *RecurrenceRepoData) UpdateRecurrence(ctx context.Context, rule Recurrence) error {
+	sql, params, err := goqu.Update(recurrencesTableName).
+		Set(goqu.Record{
+			"weekday":            rule.Weekday,
+			"until":              rule.Until,
+			"materialized_until": rule.MaterializedUntil,
+		}).
+		Where(goqu.Ex{"uuid": rule.ID.String()}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := rr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Update() {
+		return ErrNotUpdated
+	}
+	return nil
+}
+
+func (rr *RecurrenceRepoData) SetMaterializedUntil(ctx context.Context, id uuid.UUID, date time.Time) error {
+	sql, params, err := goqu.Update(recurrencesTableName).
+		Set(goqu.Record{"materialized_until": date.Format(utils.DateFormat)}).
+		Where(goqu.Ex{"uuid": id.String()}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = rr.conn.Exec(ctx, sql, params...)
+	return err
+}
+
+func (rr *RecurrenceRepoData) DeleteRecurrence(ctx context.Context, id uuid.UUID) error {
+	sql, params, err := goqu.Delete(recurrencesTableName).
+		Where(goqu.Ex{"uuid": id.String()}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := rr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Delete() {
+		return ErrNotDeleted
+	}
+	return nil
+}
+
+func (rr *RecurrenceRepoData) GetRecurrences(ctx context.Context, chatID int64) ([]Recurrence, error) {
+	sql, params, err := goqu.From(recurrencesTableName).
+		Select(Recurrence{}).
+		Where(goqu.Ex{"chat_id": chatID}).
+		Order(goqu.I("created_at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := rr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Recurrence])
+}
+
+func (rr *RecurrenceRepoData) GetActiveRecurrences(ctx context.Context, from time.Time) ([]Recurrence, error) {
+	sql, params, err := goqu.From(recurrencesTableName).
+		Select(Recurrence{}).
+		Where(goqu.Or(
+			goqu.C("until").IsNull(),
+			goqu.C("until").Gte(from.Format(utils.DateFormat)),
+		)).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := rr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Recurrence])
+}
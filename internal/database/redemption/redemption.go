@@ -0,0 +1,49 @@
+package redemption
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const redemptionsTableName = "redemptions"
+
+type RedemptionRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var RedemptionRepo RedemptionRepoer
+
+type RedemptionRepoer interface {
+	AddRedemption(ctx context.Context, r Redemption) error
+	DeleteRedemption(ctx context.Context, id uuid.UUID) error
+	// Redemptions in chat taken between from and to inclusive
+	GetRedemptions(ctx context.Context, chatID int64, from, to time.Time) ([]Redemption, error)
+}
+
+// Compensation days off taken by operator
+type Redemption struct {
+	ID     uuid.UUID `db:"uuid"`
+	ChatID int64     `db:"chat_id"`
+	UserID int64     `db:"user_id"`
+	// Number of days off taken
+	Days int `db:"days"`
+	// Day of redemption, counts against balance from then on
+	TakenOn time.Time `db:"taken_on"`
+	Note    string    `db:"note"`
+	// Admin who recorded redemption
+	Actor     string    `db:"actor"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func InitRedemptionRepo(ctx context.Context, dsn string) (RedemptionRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &RedemptionRepoData{conn: conn}
+	RedemptionRepo = result
+	return result, nil
+}
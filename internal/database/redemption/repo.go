@@ -0,0 +1,91 @@
+package redemption
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/google/uuid"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotInserted = errors.New("pgx CommandTag is not INSERT")
+	ErrNotDeleted  = errors.New("pgx CommandTag is not DELETE")
+)
+
+var _ RedemptionRepoer = &RedemptionRepoData{}
+
+func (rr *RedemptionRepoData) AddRedemption(ctx context.Context, r Redemption) error {
+	sql, params, err := goqu.Insert(redemptionsTableName).Rows(r).ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := rr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Insert() {
+		return ErrNotInserted
+	}
+	return nil
+}
+
+func (rr *RedemptionRepoData) DeleteRedemption(ctx context.Context, id uuid.UUID) error {
+	sql, params, err := goqu.Delete(redemptionsTableName).
+		Where(goqu.Ex{"uuid": id.String()}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := rr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Delete() {
+		return ErrNotDeleted
+	}
+	return nil
+}
+
+func (rr *RedemptionRepoData) GetRedemptions(
+	ctx context.Context,
+	chatID int64,
+	from, to time.Time,
+) ([]Redemption, error) {
+	sql, params, err := goqu.From(redemptionsTableName).
+		Select(Redemption{}).
+		Where(
+			goqu.C("chat_id").Eq(chatID),
+			goqu.C("taken_on").Between(exp.NewRangeVal(
+				from.Format(utils.DateFormat),
+				to.Format(utils.DateFormat),
+			)),
+		).
+		Order(goqu.I("taken_on").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := rr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Redemption])
+}
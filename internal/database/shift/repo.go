@@ -0,0 +1,72 @@
+package shift
+
+import (
+	"context"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ ShiftRepoer = &ShiftRepoData{}
+
+func (sr *ShiftRepoData) GetTemplates(ctx context.Context, chatID int64) ([]Template, error) {
+	sql, params, err := goqu.From(shiftTemplatesTableName).
+		Select(Template{}).
+		Where(goqu.Ex{"chat_id": chatID}).
+		Order(goqu.I("position").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := sr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Template])
+}
+
+func (sr *ShiftRepoData) SetTemplates(ctx context.Context, chatID int64, templates []Template) error {
+	return pgx.BeginFunc(ctx, sr.conn, func(tx pgx.Tx) error {
+		sql, params, err := goqu.Delete(shiftTemplatesTableName).
+			Where(goqu.Ex{"chat_id": chatID}).
+			ToSQL()
+		if err != nil {
+			return err
+		}
+		logger.Log.Debug().Str("sql", sql).Send()
+
+		if _, err := tx.Exec(ctx, sql, params...); err != nil {
+			return err
+		}
+		if len(templates) == 0 {
+			return nil
+		}
+
+		for i := range templates {
+			templates[i].ChatID = chatID
+			templates[i].Position = i + 1
+		}
+		sql, params, err = goqu.Insert(shiftTemplatesTableName).Rows(templates).ToSQL()
+		if err != nil {
+			return err
+		}
+		logger.Log.Debug().Str("sql", sql).Send()
+
+		_, err = tx.Exec(ctx, sql, params...)
+		return err
+	})
+}
+
+func (sr ShiftRepoData) Close() error {
+	sr.conn.Close()
+	return nil
+}
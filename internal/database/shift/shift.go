@@ -0,0 +1,160 @@
+package shift
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+const (
+	shiftTemplatesTableName = "shift_templates"
+	minutesInHour           = 60
+	minutesInDay            = utils.HoursInDay * minutesInHour
+	templateFormat          = "%02d:%02d-%02d:%02d"
+)
+
+type ShiftRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var ShiftRepo ShiftRepoer
+
+type ShiftRepoer interface {
+	GetTemplates(ctx context.Context, chatID int64) ([]Template, error)
+	// Replace chat templates. Empty list switches chat back to whole days.
+	SetTemplates(ctx context.Context, chatID int64, templates []Template) error
+}
+
+// Daily shift of chat, e.g. 08:00-16:00.
+// Times are wall clock of the bot, like dates of assignments.
+type Template struct {
+	ChatID int64 `db:"chat_id"`
+	// Order of shift within day starting from 1
+	Position int `db:"position"`
+	// Minutes since midnight
+	StartMinute int `db:"start_minute"`
+	// Minutes since midnight. Shift ends next day if it is not after start.
+	EndMinute int `db:"end_minute"`
+}
+
+func parseClock(clock string) (int, error) {
+	var hours, minutes int
+	_, err := fmt.Sscanf(clock, "%d:%d", &hours, &minutes)
+	if err != nil || hours < 0 || hours > utils.HoursInDay || minutes < 0 || minutes >= minutesInHour {
+		return 0, fmt.Errorf("'%s' is not a time, try HH:MM", clock)
+	}
+	minute := hours*minutesInHour + minutes
+	if minute > minutesInDay {
+		return 0, fmt.Errorf("'%s' is not a time, try HH:MM", clock)
+	}
+	return minute, nil
+}
+
+// Parse start of shift in HH:MM format into minutes since midnight
+func ParseClock(clock string) (int, error) {
+	minute, err := parseClock(clock)
+	if err != nil {
+		return 0, err
+	}
+	return minute % minutesInDay, nil
+}
+
+// Parse shift in HH:MM-HH:MM format
+func ParseTemplate(raw string) (Template, error) {
+	start, end, ok := strings.Cut(raw, "-")
+	if !ok || start == "" || end == "" {
+		return Template{}, fmt.Errorf("'%s' is not a shift, try HH:MM-HH:MM", raw)
+	}
+
+	startMinute, err := ParseClock(start)
+	if err != nil {
+		return Template{}, err
+	}
+	endMinute, err := parseClock(end)
+	if err != nil {
+		return Template{}, err
+	}
+	endMinute %= minutesInDay
+	if startMinute == endMinute {
+		return Template{}, fmt.Errorf("shift '%s' is empty", raw)
+	}
+	return Template{StartMinute: startMinute, EndMinute: endMinute}, nil
+}
+
+func (t Template) String() string {
+	end := t.EndMinute
+	if end == 0 {
+		end = minutesInDay
+	}
+	return fmt.Sprintf(
+		templateFormat,
+		t.StartMinute/minutesInHour,
+		t.StartMinute%minutesInHour,
+		end/minutesInHour,
+		end%minutesInHour,
+	)
+}
+
+// Start and end of shift on given day
+func (t Template) Bounds(day time.Time) (time.Time, time.Time) {
+	day = utils.GetDate(day)
+	start := day.Add(time.Duration(t.StartMinute) * time.Minute)
+	end := day.Add(time.Duration(t.EndMinute) * time.Minute)
+	if !end.After(start) {
+		end = end.Add(utils.DayDuration)
+	}
+	return start, end
+}
+
+// Minutes since midnight of shift start and end,
+// end is past midnight for shifts ending next day
+func (t Template) span() (int, int) {
+	end := t.EndMinute
+	if end <= t.StartMinute {
+		end += minutesInDay
+	}
+	return t.StartMinute, end
+}
+
+// Shifts share some time, including shifts running past midnight
+func (t Template) Overlaps(other Template) bool {
+	start, end := t.span()
+	otherStart, otherEnd := other.span()
+	for _, offset := range []int{-minutesInDay, 0, minutesInDay} {
+		if start < otherEnd+offset && otherStart+offset < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds of duty in chats without shifts
+func WholeDay(day time.Time) (time.Time, time.Time) {
+	day = utils.GetDate(day)
+	return day, day.Add(utils.DayDuration)
+}
+
+// Find template starting at given minute since midnight
+func FindByStart(templates []Template, startMinute int) (Template, bool) {
+	for _, t := range templates {
+		if t.StartMinute == startMinute {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+func InitShiftRepo(ctx context.Context, dsn string) (ShiftRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &ShiftRepoData{conn: conn}
+	ShiftRepo = result
+	return result, nil
+}
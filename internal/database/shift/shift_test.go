@@ -0,0 +1,68 @@
+package shift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate("08:00-16:30")
+	assert.NoError(t, err)
+	assert.Equal(t, 8*60, tmpl.StartMinute)
+	assert.Equal(t, 16*60+30, tmpl.EndMinute)
+	assert.Equal(t, "08:00-16:30", tmpl.String())
+
+	tmpl, err = ParseTemplate("16:00-24:00")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, tmpl.EndMinute)
+	assert.Equal(t, "16:00-24:00", tmpl.String())
+
+	for _, raw := range []string{"", "08:00", "08:00-08:00", "25:00-01:00", "08:60-09:00", "a-b"} {
+		_, err = ParseTemplate(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	day := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+	start, end := Template{StartMinute: 8 * 60, EndMinute: 16 * 60}.Bounds(day)
+	assert.Equal(t, day.Add(8*time.Hour), start)
+	assert.Equal(t, day.Add(16*time.Hour), end)
+
+	// Night shift ends next day
+	start, end = Template{StartMinute: 22 * 60, EndMinute: 6 * 60}.Bounds(day)
+	assert.Equal(t, day.Add(22*time.Hour), start)
+	assert.Equal(t, day.Add(30*time.Hour), end)
+
+	start, end = WholeDay(day.Add(5 * time.Hour))
+	assert.Equal(t, day, start)
+	assert.Equal(t, day.Add(24*time.Hour), end)
+}
+
+func TestOverlaps(t *testing.T) {
+	cases := []struct {
+		first, second string
+		overlaps      bool
+	}{
+		{"00:00-08:00", "08:00-16:00", false},
+		{"08:00-16:00", "12:00-20:00", true},
+		{"08:00-16:00", "08:00-09:00", true},
+		{"09:00-10:00", "08:00-16:00", true},
+		{"22:00-06:00", "06:00-14:00", false},
+		{"22:00-06:00", "05:00-07:00", true},
+		{"22:00-06:00", "00:00-01:00", true},
+		{"16:00-24:00", "00:00-08:00", false},
+		{"20:00-04:00", "23:00-02:00", true},
+	}
+	for _, c := range cases {
+		first, err := ParseTemplate(c.first)
+		assert.NoError(t, err)
+		second, err := ParseTemplate(c.second)
+		assert.NoError(t, err)
+		assert.Equal(t, c.overlaps, first.Overlaps(second), c.first+" "+c.second)
+		assert.Equal(t, c.overlaps, second.Overlaps(first), c.second+" "+c.first)
+	}
+}
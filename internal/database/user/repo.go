@@ -0,0 +1,160 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ UserRepoer = &UserRepoData{}
+
+// errors
+var (
+	ErrNotFound = errors.New("user not found")
+)
+
+// Any goqu dataset
+type sqlBuilder interface {
+	ToSQL() (string, []interface{}, error)
+}
+
+func exec(ctx context.Context, tx pgx.Tx, query sqlBuilder) error {
+	sql, params, err := query.ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	_, err = tx.Exec(ctx, sql, params...)
+	return err
+}
+
+// Save fresh user data from telegram.
+// Placeholder user with the same username is merged into this one
+// and operator names of user assignments are updated.
+func (ur *UserRepoData) UpsertUser(ctx context.Context, u User) error {
+	return pgx.BeginFunc(ctx, ur.conn, func(tx pgx.Tx) error {
+		err := exec(ctx, tx, goqu.Insert(usersTableName).
+			Rows(u).
+			OnConflict(goqu.DoUpdate("id", goqu.Record{
+				"username":   u.Username,
+				"first_name": u.FirstName,
+				"last_name":  u.LastName,
+				"updated_at": u.UpdatedAt,
+			})))
+		if err != nil {
+			return err
+		}
+
+		if u.Username != "" {
+			err = ur.takeOverUsername(ctx, tx, u)
+			if err != nil {
+				return err
+			}
+		}
+
+		return exec(ctx, tx, goqu.Update(assignmentsTableName).
+			Set(goqu.Record{"operator": u.DisplayName()}).
+			Where(goqu.Ex{
+				"user_id":  u.ID,
+				"operator": goqu.Op{"neq": u.DisplayName()},
+			}))
+	})
+}
+
+// Rows of placeholders clashing with rows user already has.
+// User rows are newer, so they win.
+func dropConflicts(column userColumn, placeholders *goqu.SelectDataset, userID int64) *goqu.DeleteDataset {
+	const target = "target"
+	same := []exp.Expression{goqu.I(target + "." + column.Column).Eq(userID)}
+	for _, key := range column.Key {
+		same = append(same, goqu.I(target+"."+key).Eq(goqu.I(column.Table+"."+key)))
+	}
+	return goqu.Delete(column.Table).Where(
+		goqu.C(column.Column).In(placeholders),
+		goqu.L("EXISTS ?", goqu.From(goqu.T(column.Table).As(target)).
+			Select(goqu.L("1")).
+			Where(same...)),
+	)
+}
+
+// Usernames are unique in telegram, so anybody else holding
+// user's username either is a placeholder or renamed since.
+// Data of placeholder moves to the user.
+func (ur *UserRepoData) takeOverUsername(ctx context.Context, tx pgx.Tx, u User) error {
+	placeholders := goqu.From(usersTableName).
+		Select("id").
+		Where(goqu.Ex{"username": u.Username, "id": goqu.Op{"lt": 0}})
+
+	for _, column := range userColumns {
+		if column.Key != nil {
+			err := exec(ctx, tx, dropConflicts(column, placeholders, u.ID))
+			if err != nil {
+				return err
+			}
+		}
+		err := exec(ctx, tx, goqu.Update(column.Table).
+			Set(goqu.Record{column.Column: u.ID}).
+			Where(goqu.C(column.Column).In(placeholders)))
+		if err != nil {
+			return err
+		}
+	}
+
+	err := exec(ctx, tx, goqu.Delete(usersTableName).
+		Where(goqu.Ex{"username": u.Username, "id": goqu.Op{"lt": 0}}))
+	if err != nil {
+		return err
+	}
+
+	return exec(ctx, tx, goqu.Update(usersTableName).
+		Set(goqu.Record{"username": ""}).
+		Where(goqu.Ex{"username": u.Username, "id": goqu.Op{"neq": u.ID}}))
+}
+
+func (ur *UserRepoData) getOne(ctx context.Context, where goqu.Ex) (User, error) {
+	sql, params, err := goqu.From(usersTableName).
+		Select(User{}).
+		Where(where).
+		ToSQL()
+	if err != nil {
+		return User{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := ur.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return User{}, err
+	}
+
+	u, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[User])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return User{}, ErrNotFound
+	case err != nil:
+		return User{}, err
+	default:
+	}
+	return u, nil
+}
+
+func (ur *UserRepoData) GetUser(ctx context.Context, id int64) (User, error) {
+	return ur.getOne(ctx, goqu.Ex{"id": id})
+}
+
+func (ur *UserRepoData) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	return ur.getOne(ctx, goqu.Ex{"username": username})
+}
+
+func (ur UserRepoData) Close() error {
+	ur.conn.Close()
+	return nil
+}
@@ -0,0 +1,102 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	usersTableName       = "users"
+	assignmentsTableName = "assignments"
+)
+
+// Column holding Telegram user ID. Placeholder user data
+// is moved to the real user when they take its username.
+type userColumn struct {
+	Table  string
+	Column string
+	// Columns unique along with user column, nil if there are none
+	Key []string
+}
+
+var userColumns = []userColumn{
+	{Table: assignmentsTableName, Column: "user_id", Key: []string{"chat_id", "starts_at"}},
+	{Table: "absences", Column: "user_id"},
+	{Table: "operator_preferences", Column: "user_id", Key: []string{"chat_id"}},
+	{Table: "recurrences", Column: "user_id"},
+	{Table: "watches", Column: "user_id", Key: []string{"chat_id", "at"}},
+	{Table: "redemptions", Column: "user_id"},
+	{Table: "pending_actions", Column: "user_id"},
+	{Table: "pending_actions", Column: "subject_id"},
+	{Table: "incidents", Column: "operator_id"},
+	{Table: "chat_settings", Column: "report_user_id"},
+}
+
+type UserRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var UserRepo UserRepoer
+
+type UserRepoer interface {
+	UpsertUser(ctx context.Context, u User) error
+	GetUser(ctx context.Context, id int64) (User, error)
+	GetUserByUsername(ctx context.Context, username string) (User, error)
+}
+
+type User struct {
+	// Telegram user ID. Negative for operators known only by username
+	ID int64 `db:"id"`
+	// Telegram username without @. Could be empty
+	Username  string `db:"username"`
+	FirstName string `db:"first_name"`
+	LastName  string `db:"last_name"`
+	// When user data was refreshed last time
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Full name of user
+func (u User) FullName() string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// Name to show in tables. Username if user has one, full name otherwise.
+func (u User) DisplayName() string {
+	if u.Username != "" {
+		return u.Username
+	}
+	return u.FullName()
+}
+
+// Username and full name are the same
+func (u User) HasSameNames(other User) bool {
+	return u.Username == other.Username && u.FirstName == other.FirstName && u.LastName == other.LastName
+}
+
+// Mention to notify user. Users without username are mentioned
+// with text mention, so message should be sent with HTML parse mode.
+func (u User) Mention() string {
+	switch {
+	case u.Username != "":
+		return "@" + u.Username
+	case u.ID < 0:
+		// Placeholder can't be mentioned
+		return html.EscapeString(u.DisplayName())
+	}
+	return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, u.ID, html.EscapeString(u.FullName()))
+}
+
+func InitUserRepo(ctx context.Context, dsn string) (UserRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &UserRepoData{conn: conn}
+	UserRepo = result
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// Real user may already have rows, e.g. watch of the same day
+func TestDropConflicts(t *testing.T) {
+	placeholders := goqu.From(usersTableName).
+		Select("id").
+		Where(goqu.Ex{"username": "alice", "id": goqu.Op{"lt": 0}})
+	column := userColumn{Table: "watches", Column: "user_id", Key: []string{"chat_id", "at"}}
+
+	sql, _, err := dropConflicts(column, placeholders, 42).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		`DELETE FROM "watches" WHERE (("user_id" IN ((SELECT "id" FROM "users" `+
+			`WHERE (("id" < 0) AND ("username" = 'alice'))))) AND EXISTS (SELECT 1 FROM "watches" AS "target" `+
+			`WHERE (("target"."user_id" = 42) AND ("target"."chat_id" = "watches"."chat_id") `+
+			`AND ("target"."at" = "watches"."at"))))`,
+		sql,
+	)
+}
+
+// Every table keeping user IDs must be listed, otherwise
+// data of placeholder is lost when real user shows up
+func TestUserColumns(t *testing.T) {
+	expected := map[string]bool{
+		"assignments.user_id":          true,
+		"absences.user_id":             true,
+		"operator_preferences.user_id": true,
+		"recurrences.user_id":          true,
+		"watches.user_id":              true,
+		"redemptions.user_id":          true,
+		"pending_actions.user_id":      true,
+		"pending_actions.subject_id":   true,
+		"incidents.operator_id":        true,
+		"chat_settings.report_user_id": true,
+	}
+	listed := make(map[string]bool)
+	for _, column := range userColumns {
+		listed[column.Table+"."+column.Column] = true
+	}
+	assert.Equal(t, expected, listed)
+}
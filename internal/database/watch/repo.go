@@ -0,0 +1,138 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/google/uuid"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotInserted = errors.New("pgx CommandTag is not INSERT")
+	ErrNotDeleted  = errors.New("pgx CommandTag is not DELETE")
+	ErrNotFound    = errors.New("watch not found")
+)
+
+var _ WatchRepoer = &WatchRepoData{}
+
+// Watching the same day twice is a no-op
+func (wr *WatchRepoData) AddWatch(ctx context.Context, w Watch) error {
+	sql, params, err := goqu.Insert(watchesTableName).
+		Rows(w).
+		OnConflict(goqu.DoNothing()).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := wr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Insert() {
+		return ErrNotInserted
+	}
+	return nil
+}
+
+func (wr *WatchRepoData) DeleteWatch(ctx context.Context, id uuid.UUID) error {
+	sql, params, err := goqu.Delete(watchesTableName).
+		Where(goqu.Ex{"uuid": id.String()}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := wr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Delete() {
+		return ErrNotDeleted
+	}
+	return nil
+}
+
+func (wr *WatchRepoData) GetWatch(ctx context.Context, id uuid.UUID) (Watch, error) {
+	sql, params, err := goqu.From(watchesTableName).
+		Select(Watch{}).
+		Where(goqu.Ex{"uuid": id.String()}).
+		ToSQL()
+	if err != nil {
+		return Watch{}, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := wr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return Watch{}, err
+	}
+
+	w, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Watch])
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return Watch{}, ErrNotFound
+	case err != nil:
+		return Watch{}, err
+	default:
+	}
+	return w, nil
+}
+
+func (wr *WatchRepoData) GetWatchers(ctx context.Context, chatID int64, at time.Time) ([]Watch, error) {
+	sql, params, err := goqu.From(watchesTableName).
+		Select(Watch{}).
+		Where(
+			goqu.C("chat_id").Eq(chatID),
+			goqu.C("at").Eq(at.Format(utils.DateFormat)),
+		).
+		Order(goqu.I("created_at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := wr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Watch])
+}
+
+func (wr *WatchRepoData) GetUserWatches(ctx context.Context, chatID, userID int64, from time.Time) ([]Watch, error) {
+	sql, params, err := goqu.From(watchesTableName).
+		Select(Watch{}).
+		Where(
+			goqu.C("chat_id").Eq(chatID),
+			goqu.C("user_id").Eq(userID),
+			goqu.C("at").Gte(from.Format(utils.DateFormat)),
+		).
+		Order(goqu.I("at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := wr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Watch])
+}
@@ -0,0 +1,47 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const watchesTableName = "watches"
+
+type WatchRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var WatchRepo WatchRepoer
+
+type WatchRepoer interface {
+	AddWatch(ctx context.Context, w Watch) error
+	DeleteWatch(ctx context.Context, id uuid.UUID) error
+	GetWatch(ctx context.Context, id uuid.UUID) (Watch, error)
+	// Users waiting for duty day in chat, first subscribed first
+	GetWatchers(ctx context.Context, chatID int64, at time.Time) ([]Watch, error)
+	// Watches of user in chat for days on or after from
+	GetUserWatches(ctx context.Context, chatID, userID int64, from time.Time) ([]Watch, error)
+}
+
+// User waiting for taken duty day to become free
+type Watch struct {
+	ID     uuid.UUID `db:"uuid"`
+	ChatID int64     `db:"chat_id"`
+	UserID int64     `db:"user_id"`
+	// Watched duty day (monday in weekly chats)
+	At        time.Time `db:"at"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func InitWatchRepo(ctx context.Context, dsn string) (WatchRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &WatchRepoData{conn: conn}
+	WatchRepo = result
+	return result, nil
+}
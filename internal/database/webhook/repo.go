@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	// Load postgres dialect for goqu
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ WebhookRepoer = &WebhookRepoData{}
+
+// errors
+var (
+	ErrNotInserted = errors.New("pgx CommandTag is not INSERT")
+	ErrNotFound    = errors.New("webhook not found")
+)
+
+func (whr *WebhookRepoData) AddWebhook(ctx context.Context, wh Webhook) error {
+	sql, params, err := goqu.Insert(webhooksTableName).Rows(wh).ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := whr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if !result.Insert() {
+		return ErrNotInserted
+	}
+	return nil
+}
+
+func (whr *WebhookRepoData) DeleteWebhook(ctx context.Context, chatID int64, url string) error {
+	sql, params, err := goqu.Delete(webhooksTableName).
+		Where(goqu.Ex{
+			"chat_id": chatID,
+			"url":     url,
+		}).ToSQL()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	result, err := whr.conn.Exec(ctx, sql, params...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Return all webhooks registered for specified chat
+func (whr *WebhookRepoData) GetWebhooks(ctx context.Context, chatID int64) ([]Webhook, error) {
+	sql, params, err := goqu.From(webhooksTableName).
+		Select(Webhook{}).
+		Where(goqu.Ex{"chat_id": chatID}).
+		Order(goqu.I("created_at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debug().Str("sql", sql).Send()
+
+	rows, err := whr.conn.Query(ctx, sql, params...)
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Webhook{}, err
+	}
+	defer rows.Close()
+
+	hooks, err := pgx.CollectRows(rows, pgx.RowToStructByName[Webhook])
+	if err != nil {
+		logger.Log.Error().Stack().Err(err).Send()
+		return []Webhook{}, err
+	}
+	return hooks, nil
+}
+
+func (whr WebhookRepoData) Close() error {
+	whr.conn.Close()
+	return nil
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const webhooksTableName = "webhooks"
+
+type WebhookRepoData struct {
+	conn *pgxpool.Pool
+}
+
+var WebhookRepo WebhookRepoer
+
+type WebhookRepoer interface {
+	AddWebhook(ctx context.Context, wh Webhook) error
+	DeleteWebhook(ctx context.Context, chatID int64, url string) error
+	GetWebhooks(ctx context.Context, chatID int64) ([]Webhook, error)
+}
+
+type Webhook struct {
+	ID uuid.UUID `db:"uuid"`
+	// Chat which events are sent to this webhook
+	ChatID int64 `db:"chat_id"`
+	// Where to POST events
+	URL string `db:"url"`
+	// Key for payload signature. Global WebhookSecret is used if empty
+	Secret string `db:"secret"`
+	// When webhook was registered
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func InitWebhookRepo(ctx context.Context, dsn string) (WebhookRepoer, error) {
+	conn, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	result := &WebhookRepoData{conn: conn}
+	WebhookRepo = result
+	return result, nil
+}
@@ -0,0 +1,198 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/webhook"
+	"github.com/FedoseevAlex/DutyBot/internal/logger"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Event types sent to webhooks
+const (
+	AssignmentCreated = "assignment.created"
+	AssignmentDeleted = "assignment.deleted"
+	AssignmentSwapped = "assignment.swapped"
+	DutyStarted       = "duty.started"
+)
+
+const (
+	SignatureHeader = "X-DutyBot-Signature"
+	EventHeader     = "X-DutyBot-Event"
+	DeliveryHeader  = "X-DutyBot-Delivery"
+	initialBackoff  = time.Second
+)
+
+var ErrNoSecret = errors.New("webhook has no signing secret")
+
+type Event struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       string      `json:"type"`
+	ChatID     int64       `json:"chat_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Delivery failure that makes no sense to retry
+// (e.g. webhook answered with 4xx status).
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+func NewEvent(eventType string, chatID int64, data interface{}) Event {
+	return Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		ChatID:     chatID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// Sign returns hex encoded HMAC-SHA256 of body
+// prefixed with algorithm name, e.g. "sha256=ab12...".
+// Receivers should compute the same value using shared secret
+// and compare it with X-DutyBot-Signature header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish sends event to every webhook registered for event chat.
+// Delivery happens in background so repo write paths
+// are not blocked by slow receivers.
+func Publish(event Event) {
+	if webhook.WebhookRepo == nil {
+		return
+	}
+
+	go func() {
+		hooks, err := webhook.WebhookRepo.GetWebhooks(context.Background(), event.ChatID)
+		if err != nil {
+			logger.Log.Error().
+				Err(err).
+				Int64("chat_id", event.ChatID).
+				Msg("Unable to get webhooks for event")
+			return
+		}
+		if len(hooks) == 0 {
+			return
+		}
+
+		body, err := json.Marshal(event)
+		if err != nil {
+			logger.Log.Error().Stack().Err(err).Send()
+			return
+		}
+
+		client := &http.Client{Timeout: viper.GetDuration("WebhookTimeout")}
+		for _, hook := range hooks {
+			go func(hook webhook.Webhook) {
+				err := deliver(client, hook, event, body, viper.GetInt("WebhookRetries"), initialBackoff)
+				if err != nil {
+					logger.Log.Error().
+						Err(err).
+						Str("url", hook.URL).
+						Str("event", event.Type).
+						Msg("Webhook delivery failed")
+				}
+			}(hook)
+		}
+	}()
+}
+
+// Try to deliver event retrying with exponential backoff
+// until it is accepted or retries are exhausted.
+func deliver(
+	client *http.Client,
+	hook webhook.Webhook,
+	event Event,
+	body []byte,
+	retries int,
+	backoff time.Duration,
+) error {
+	secret := hook.Secret
+	if secret == "" {
+		secret = viper.GetString("WebhookSecret")
+	}
+	if secret == "" {
+		// Unsigned requests can't be told from forged ones
+		return permanentError{err: ErrNoSecret}
+	}
+	signature := Sign(secret, body)
+
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		err = post(client, hook.URL, event, body, signature)
+		if err == nil {
+			return nil
+		}
+		if errors.As(err, &permanentError{}) {
+			return err
+		}
+		logger.Log.Warn().
+			Err(err).
+			Str("url", hook.URL).
+			Int("attempt", attempt+1).
+			Msg("Webhook delivery attempt failed")
+	}
+	return err
+}
+
+func post(client *http.Client, url string, event Event, body []byte, signature string) error {
+	req, err := http.NewRequestWithContext(
+		context.Background(),
+		http.MethodPost,
+		url,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return permanentError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, event.ID.String())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer utils.Close(resp.Body)
+
+	switch {
+	case resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("webhook answered with status %d", resp.StatusCode)
+	default:
+		return permanentError{err: fmt.Errorf("webhook rejected event with status %d", resp.StatusCode)}
+	}
+}
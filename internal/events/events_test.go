@@ -0,0 +1,73 @@
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FedoseevAlex/DutyBot/internal/database/webhook"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"duty.started"}`)
+
+	assert.Equal(t, Sign("secret", body), Sign("secret", body))
+	assert.NotEqual(t, Sign("secret", body), Sign("other", body))
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", Sign("secret", body))
+}
+
+func TestDeliverRetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, AssignmentCreated, r.Header.Get(EventHeader))
+		assert.Equal(t, Sign("secret", []byte("{}")), r.Header.Get(SignatureHeader))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hook := webhook.Webhook{URL: server.URL, Secret: "secret"}
+	event := NewEvent(AssignmentCreated, 1, nil)
+
+	err := deliver(server.Client(), hook, event, []byte("{}"), 5, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestDeliverStopsOnClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	hook := webhook.Webhook{URL: server.URL, Secret: "secret"}
+	event := NewEvent(AssignmentDeleted, 1, nil)
+
+	err := deliver(server.Client(), hook, event, []byte("{}"), 5, time.Millisecond)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestDeliverRefusesUnsigned(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	hook := webhook.Webhook{URL: server.URL}
+	event := NewEvent(AssignmentCreated, 1, nil)
+
+	err := deliver(server.Client(), hook, event, []byte("{}"), 5, time.Millisecond)
+	assert.ErrorIs(t, err, ErrNoSecret)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
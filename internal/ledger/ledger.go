@@ -0,0 +1,106 @@
+package ledger
+
+import (
+	"sort"
+	"time"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/redemption"
+)
+
+// Days off earned for a duty day of each kind
+type Rates struct {
+	Weekend int
+	Holiday int
+}
+
+// Compensation balance of operator
+type Balance struct {
+	UserID   int64
+	Operator string
+	// Duty days on weekends and public holidays
+	Weekends int
+	Holidays int
+	// Days off earned, taken and left
+	Earned   int
+	Redeemed int
+	Left     int
+}
+
+// Compute compensation balances from assignments and redemptions.
+// Only primary duty days up to until are counted: shifts count
+// their day, weekly duties count every day of the week.
+// workingDays must cover all counted days.
+// Result is sorted by days left, largest balance first.
+func Compute(
+	assignments []assignment.Assignment,
+	redemptions []redemption.Redemption,
+	workingDays calendar.TimeSet,
+	rates Rates,
+	until time.Time,
+) []Balance {
+	byUser := make(map[int64]*Balance)
+	get := func(userID int64) *Balance {
+		b, ok := byUser[userID]
+		if !ok {
+			b = &Balance{UserID: userID}
+			byUser[userID] = b
+		}
+		return b
+	}
+
+	for _, as := range assignments {
+		if as.Operator == "" || as.IsBackup() {
+			continue
+		}
+		b := get(as.UserID)
+		b.Operator = as.Operator
+
+		for _, day := range as.Days() {
+			if day.After(until) {
+				break
+			}
+			switch calendar.GetDayType(day, workingDays) {
+			case calendar.Weekend:
+				b.Weekends++
+				b.Earned += rates.Weekend
+			case calendar.PublicHoliday:
+				b.Holidays++
+				b.Earned += rates.Holiday
+			case calendar.WorkingDay:
+			}
+		}
+	}
+
+	for _, r := range redemptions {
+		get(r.UserID).Redeemed += r.Days
+	}
+
+	result := make([]Balance, 0, len(byUser))
+	for _, b := range byUser {
+		b.Left = b.Earned - b.Redeemed
+		if b.Earned == 0 && b.Redeemed == 0 {
+			continue
+		}
+		result = append(result, *b)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Left != result[j].Left {
+			return result[i].Left > result[j].Left
+		}
+		return result[i].Operator < result[j].Operator
+	})
+	return result
+}
+
+// Find balance of user, zero balance if user has none
+func Find(balances []Balance, userID int64) Balance {
+	for _, b := range balances {
+		if b.UserID == userID {
+			return b
+		}
+	}
+	return Balance{UserID: userID}
+}
@@ -0,0 +1,119 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/database/redemption"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+func date(day int) time.Time {
+	// 2023-01-02 is Monday
+	return time.Date(2023, time.January, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Weekdays between from and to inclusive
+func weekdays(from, to time.Time) calendar.TimeSet {
+	days := calendar.TimeSet{}
+	for d := from; !d.After(to); d = d.Add(utils.DayDuration) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			days.Add(d)
+		}
+	}
+	return days
+}
+
+// Weekdays of January 2023 except holidays 2nd and 3rd
+func workingDays() calendar.TimeSet {
+	days := weekdays(date(1), date(31))
+	days.Remove(date(2))
+	days.Remove(date(3))
+	return days
+}
+
+func TestCompute(t *testing.T) {
+	assignments := []assignment.Assignment{
+		// Holiday and weekend
+		{At: date(2), UserID: 1, Operator: "alice", Role: assignment.RolePrimary},
+		{At: date(7), UserID: 1, Operator: "alice", Role: assignment.RolePrimary},
+		// Working day
+		{At: date(4), UserID: 2, Operator: "bob", Role: assignment.RolePrimary},
+		// Backups earn nothing
+		{At: date(8), UserID: 2, Operator: "bob", Role: assignment.RoleSecondary},
+		// Holiday shift
+		{
+			At:       date(3),
+			StartsAt: date(3).Add(9 * time.Hour),
+			EndsAt:   date(3).Add(18 * time.Hour),
+			UserID:   2,
+			Operator: "bob",
+			Role:     assignment.RolePrimary,
+		},
+		// After until
+		{At: date(14), UserID: 2, Operator: "bob", Role: assignment.RolePrimary},
+	}
+	redemptions := []redemption.Redemption{
+		{UserID: 1, Days: 1},
+		{UserID: 3, Days: 1},
+	}
+
+	result := Compute(assignments, redemptions, workingDays(), Rates{Weekend: 1, Holiday: 2}, date(10))
+	assert.Len(t, result, 3)
+
+	alice := result[0]
+	assert.Equal(t, "alice", alice.Operator)
+	assert.Equal(t, 1, alice.Weekends)
+	assert.Equal(t, 1, alice.Holidays)
+	assert.Equal(t, 3, alice.Earned)
+	assert.Equal(t, 1, alice.Redeemed)
+	assert.Equal(t, 2, alice.Left)
+
+	bob := result[1]
+	assert.Equal(t, "bob", bob.Operator)
+	assert.Equal(t, 0, bob.Weekends)
+	assert.Equal(t, 1, bob.Holidays)
+	assert.Equal(t, 2, bob.Left)
+
+	assert.Equal(t, -1, Find(result, 3).Left)
+	assert.Equal(t, 0, Find(result, 4).Left)
+}
+
+func TestComputeWeek(t *testing.T) {
+	week := assignment.Assignment{
+		At:       date(2),
+		StartsAt: date(2),
+		EndsAt:   date(9),
+		UserID:   1,
+		Operator: "alice",
+		Role:     assignment.RolePrimary,
+	}
+
+	result := Compute([]assignment.Assignment{week}, nil, workingDays(), Rates{Weekend: 1, Holiday: 1}, date(31))
+	assert.Len(t, result, 1)
+	assert.Equal(t, 2, result[0].Weekends)
+	assert.Equal(t, 2, result[0].Holidays)
+	assert.Equal(t, 4, result[0].Left)
+}
+
+func TestComputeRunsAcrossYears(t *testing.T) {
+	newYearsEve := time.Date(2022, time.December, 31, 0, 0, 0, 0, time.UTC)
+	assignments := []assignment.Assignment{
+		{At: newYearsEve, UserID: 1, Operator: "alice", Role: assignment.RolePrimary},
+		{At: date(7), UserID: 1, Operator: "alice", Role: assignment.RolePrimary},
+	}
+	redemptions := []redemption.Redemption{{UserID: 1, Days: 1, TakenOn: date(9)}}
+
+	result := Compute(
+		assignments,
+		redemptions,
+		weekdays(newYearsEve, date(31)),
+		Rates{Weekend: 1, Holiday: 1},
+		date(10),
+	)
+	assert.Equal(t, []Balance{{UserID: 1, Operator: "alice", Weekends: 2, Earned: 2, Redeemed: 1, Left: 1}}, result)
+}
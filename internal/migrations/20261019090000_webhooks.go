@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upWebhooks, downWebhooks)
+}
+
+func upWebhooks(tx *sql.Tx) error {
+	createWebhooks := `
+	CREATE TABLE webhooks (
+		uuid UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+		chat_id BIGINT NOT NULL,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(chat_id, url)
+	)
+	`
+	_, err := tx.Exec(createWebhooks)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downWebhooks(tx *sql.Tx) error {
+	dropWebhooks := "DROP TABLE webhooks"
+	_, err := tx.Exec(dropWebhooks)
+	if err != nil {
+		return err
+	}
+	return nil
+}
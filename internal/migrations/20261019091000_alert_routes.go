@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAlertRoutes, downAlertRoutes)
+}
+
+func upAlertRoutes(tx *sql.Tx) error {
+	createAlertRoutes := `
+	CREATE TABLE alert_routes (
+		route TEXT PRIMARY KEY,
+		chat_id BIGINT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+	`
+	_, err := tx.Exec(createAlertRoutes)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downAlertRoutes(tx *sql.Tx) error {
+	dropAlertRoutes := "DROP TABLE alert_routes"
+	_, err := tx.Exec(dropAlertRoutes)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upIncidents, downIncidents)
+}
+
+func upIncidents(tx *sql.Tx) error {
+	createIncidents := `
+	CREATE TABLE incidents (
+		uuid UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+		chat_id BIGINT NOT NULL,
+		message_id BIGINT NOT NULL DEFAULT 0,
+		group_key TEXT NOT NULL DEFAULT '',
+		title TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'open',
+		operator TEXT NOT NULL DEFAULT '',
+		escalation_level INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		escalated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		acknowledged_by TEXT NOT NULL DEFAULT '',
+		acknowledged_at TIMESTAMP,
+		resolved_by TEXT NOT NULL DEFAULT '',
+		resolved_at TIMESTAMP
+	)
+	`
+	_, err := tx.Exec(createIncidents)
+	if err != nil {
+		return err
+	}
+
+	createStatusIndex := "CREATE INDEX incidents_status_idx ON incidents (status, escalated_at)"
+	_, err = tx.Exec(createStatusIndex)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downIncidents(tx *sql.Tx) error {
+	dropIncidents := "DROP TABLE incidents"
+	_, err := tx.Exec(dropIncidents)
+	if err != nil {
+		return err
+	}
+	return nil
+}
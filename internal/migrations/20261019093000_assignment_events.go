@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAssignmentEvents, downAssignmentEvents)
+}
+
+func upAssignmentEvents(tx *sql.Tx) error {
+	createAssignmentEvents := `
+	CREATE TABLE assignment_events (
+		uuid UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+		assignment_uuid UUID NOT NULL,
+		kind TEXT NOT NULL,
+		chat_id BIGINT NOT NULL,
+		at DATE NOT NULL,
+		operator TEXT NOT NULL DEFAULT '',
+		actor TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+	`
+	_, err := tx.Exec(createAssignmentEvents)
+	if err != nil {
+		return err
+	}
+
+	createChatIndex := "CREATE INDEX assignment_events_chat_idx ON assignment_events (chat_id, created_at)"
+	_, err = tx.Exec(createChatIndex)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downAssignmentEvents(tx *sql.Tx) error {
+	dropAssignmentEvents := "DROP TABLE assignment_events"
+	_, err := tx.Exec(dropAssignmentEvents)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upUsers, downUsers)
+}
+
+func upUsers(tx *sql.Tx) error {
+	createUsers := `
+	CREATE TABLE users (
+		id BIGINT PRIMARY KEY,
+		username TEXT NOT NULL DEFAULT '',
+		first_name TEXT NOT NULL DEFAULT '',
+		last_name TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+	`
+	_, err := tx.Exec(createUsers)
+	if err != nil {
+		return err
+	}
+
+	// Telegram IDs of existing operators are unknown, so they get
+	// negative placeholder IDs. Placeholder is replaced with real user
+	// once somebody with the same username talks to the bot.
+	// Assignments made by users without username are kept
+	// under single "unknown" placeholder.
+	backfillUsers := `
+	INSERT INTO users (id, username, first_name)
+	SELECT -ROW_NUMBER() OVER (ORDER BY operator), operator,
+		CASE WHEN operator = '' THEN 'unknown' ELSE '' END
+	FROM (SELECT DISTINCT COALESCE(operator, '') AS operator FROM assignments) AS operators
+	`
+	_, err = tx.Exec(backfillUsers)
+	if err != nil {
+		return err
+	}
+
+	return linkAssignmentsToUsers(tx)
+}
+
+// Point assignments to their users and keep them unique per user instead of username
+func linkAssignmentsToUsers(tx *sql.Tx) error {
+	addUserID := `
+	ALTER TABLE assignments
+	ADD COLUMN user_id BIGINT REFERENCES users(id) ON UPDATE CASCADE
+	`
+	_, err := tx.Exec(addUserID)
+	if err != nil {
+		return err
+	}
+
+	backfillAssignments := `
+	UPDATE assignments
+	SET user_id = users.id,
+		operator = CASE WHEN users.username = '' THEN users.first_name ELSE users.username END
+	FROM users WHERE users.username = COALESCE(assignments.operator, '')
+	`
+	_, err = tx.Exec(backfillAssignments)
+	if err != nil {
+		return err
+	}
+
+	replaceUnique := `
+	ALTER TABLE assignments
+	ALTER COLUMN user_id SET NOT NULL,
+	DROP CONSTRAINT IF EXISTS assignments_operator_chat_id_at_key,
+	ADD CONSTRAINT assignments_user_id_chat_id_at_key UNIQUE (user_id, chat_id, at)
+	`
+	_, err = tx.Exec(replaceUnique)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downUsers(tx *sql.Tx) error {
+	restoreUnique := `
+	ALTER TABLE assignments
+	DROP CONSTRAINT assignments_user_id_chat_id_at_key,
+	ADD CONSTRAINT assignments_operator_chat_id_at_key UNIQUE (operator, chat_id, at)
+	`
+	_, err := tx.Exec(restoreUnique)
+	if err != nil {
+		return err
+	}
+
+	dropUserID := "ALTER TABLE assignments DROP COLUMN user_id"
+	_, err = tx.Exec(dropUserID)
+	if err != nil {
+		return err
+	}
+
+	dropUsers := "DROP TABLE users"
+	_, err = tx.Exec(dropUsers)
+	if err != nil {
+		return err
+	}
+	return nil
+}
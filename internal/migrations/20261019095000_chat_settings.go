@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upChatSettings, downChatSettings)
+}
+
+func upChatSettings(tx *sql.Tx) error {
+	createChatSettings := `
+	CREATE TABLE chat_settings (
+		chat_id BIGINT PRIMARY KEY,
+		announce_thread_id BIGINT NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+	`
+	_, err := tx.Exec(createChatSettings)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downChatSettings(tx *sql.Tx) error {
+	dropChatSettings := "DROP TABLE chat_settings"
+	_, err := tx.Exec(dropChatSettings)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upKeyboards, downKeyboards)
+}
+
+func upKeyboards(tx *sql.Tx) error {
+	createKeyboards := `
+	CREATE TABLE keyboards (
+		chat_id BIGINT NOT NULL,
+		message_id BIGINT NOT NULL,
+		view TEXT NOT NULL,
+		shown_from DATE NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (chat_id, message_id)
+	)
+	`
+	_, err := tx.Exec(createKeyboards)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downKeyboards(tx *sql.Tx) error {
+	dropKeyboards := "DROP TABLE keyboards"
+	_, err := tx.Exec(dropKeyboards)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upShifts, downShifts)
+}
+
+func upShifts(tx *sql.Tx) error {
+	createShiftTemplates := `
+	CREATE TABLE shift_templates (
+		chat_id BIGINT NOT NULL,
+		position INTEGER NOT NULL,
+		start_minute INTEGER NOT NULL,
+		end_minute INTEGER NOT NULL,
+		PRIMARY KEY (chat_id, position)
+	)
+	`
+	_, err := tx.Exec(createShiftTemplates)
+	if err != nil {
+		return err
+	}
+
+	addShiftBounds := `
+	ALTER TABLE assignments
+	ADD COLUMN starts_at TIMESTAMP,
+	ADD COLUMN ends_at TIMESTAMP
+	`
+	_, err = tx.Exec(addShiftBounds)
+	if err != nil {
+		return err
+	}
+
+	// Existing assignments take whole day
+	fillShiftBounds := `
+	UPDATE assignments
+	SET starts_at = at, ends_at = at + INTERVAL '1 day'
+	`
+	_, err = tx.Exec(fillShiftBounds)
+	if err != nil {
+		return err
+	}
+
+	alterAssignments := `
+	ALTER TABLE assignments
+	ALTER COLUMN starts_at SET NOT NULL,
+	ALTER COLUMN ends_at SET NOT NULL,
+	DROP CONSTRAINT assignments_user_id_chat_id_at_key,
+	ADD CONSTRAINT assignments_user_id_chat_id_starts_at_key UNIQUE (user_id, chat_id, starts_at)
+	`
+	_, err = tx.Exec(alterAssignments)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downShifts(tx *sql.Tx) error {
+	alterAssignments := `
+	ALTER TABLE assignments
+	DROP CONSTRAINT assignments_user_id_chat_id_starts_at_key,
+	ADD CONSTRAINT assignments_user_id_chat_id_at_key UNIQUE (user_id, chat_id, at),
+	DROP COLUMN starts_at,
+	DROP COLUMN ends_at
+	`
+	_, err := tx.Exec(alterAssignments)
+	if err != nil {
+		return err
+	}
+
+	dropShiftTemplates := "DROP TABLE shift_templates"
+	_, err = tx.Exec(dropShiftTemplates)
+	if err != nil {
+		return err
+	}
+	return nil
+}
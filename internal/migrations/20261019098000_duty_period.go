@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upDutyPeriod, downDutyPeriod)
+}
+
+func upDutyPeriod(tx *sql.Tx) error {
+	addPeriod := "ALTER TABLE chat_settings ADD COLUMN duty_period TEXT NOT NULL DEFAULT 'day'"
+	_, err := tx.Exec(addPeriod)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downDutyPeriod(tx *sql.Tx) error {
+	dropPeriod := "ALTER TABLE chat_settings DROP COLUMN duty_period"
+	_, err := tx.Exec(dropPeriod)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAssignmentRoles, downAssignmentRoles)
+}
+
+func upAssignmentRoles(tx *sql.Tx) error {
+	addRole := "ALTER TABLE assignments ADD COLUMN role TEXT NOT NULL DEFAULT 'primary'"
+	_, err := tx.Exec(addRole)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downAssignmentRoles(tx *sql.Tx) error {
+	deleteBackups := "DELETE FROM assignments WHERE role <> 'primary'"
+	_, err := tx.Exec(deleteBackups)
+	if err != nil {
+		return err
+	}
+
+	dropRole := "ALTER TABLE assignments DROP COLUMN role"
+	_, err = tx.Exec(dropRole)
+	if err != nil {
+		return err
+	}
+	return nil
+}
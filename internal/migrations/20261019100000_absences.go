@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAbsences, downAbsences)
+}
+
+func upAbsences(tx *sql.Tx) error {
+	createAbsences := `
+	CREATE TABLE absences (
+		uuid UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		starts_on DATE NOT NULL,
+		ends_on DATE NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+	`
+	_, err := tx.Exec(createAbsences)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downAbsences(tx *sql.Tx) error {
+	dropAbsences := "DROP TABLE absences"
+	_, err := tx.Exec(dropAbsences)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upOperatorPreferences, downOperatorPreferences)
+}
+
+func upOperatorPreferences(tx *sql.Tx) error {
+	createPreferences := `
+	CREATE TABLE operator_preferences (
+		chat_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		avoid_weekdays INTEGER NOT NULL DEFAULT 0,
+		max_per_month INTEGER NOT NULL DEFAULT 0,
+		no_consecutive BOOLEAN NOT NULL DEFAULT FALSE,
+		no_after_holiday BOOLEAN NOT NULL DEFAULT FALSE,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (chat_id, user_id)
+	)
+	`
+	_, err := tx.Exec(createPreferences)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downOperatorPreferences(tx *sql.Tx) error {
+	dropPreferences := "DROP TABLE operator_preferences"
+	_, err := tx.Exec(dropPreferences)
+	if err != nil {
+		return err
+	}
+	return nil
+}
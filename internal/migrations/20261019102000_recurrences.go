@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upRecurrences, downRecurrences)
+}
+
+func upRecurrences(tx *sql.Tx) error {
+	createRecurrences := `
+	CREATE TABLE recurrences (
+		uuid UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+		chat_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		operator TEXT NOT NULL,
+		weekday INTEGER NOT NULL,
+		role TEXT NOT NULL DEFAULT 'primary',
+		until DATE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+	`
+	_, err := tx.Exec(createRecurrences)
+	if err != nil {
+		return err
+	}
+
+	addRecurrence := "ALTER TABLE assignments ADD COLUMN recurrence_uuid UUID"
+	_, err = tx.Exec(addRecurrence)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downRecurrences(tx *sql.Tx) error {
+	dropRecurrence := "ALTER TABLE assignments DROP COLUMN recurrence_uuid"
+	_, err := tx.Exec(dropRecurrence)
+	if err != nil {
+		return err
+	}
+
+	dropRecurrences := "DROP TABLE recurrences"
+	_, err = tx.Exec(dropRecurrences)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upUndo, downUndo)
+}
+
+func upUndo(tx *sql.Tx) error {
+	addChange := `
+	ALTER TABLE assignment_events
+	ADD COLUMN change_uuid UUID,
+	ADD COLUMN assignment JSONB
+	`
+	_, err := tx.Exec(addChange)
+	if err != nil {
+		return err
+	}
+
+	// Every old event is a change of its own
+	fillChange := "UPDATE assignment_events SET change_uuid = uuid"
+	_, err = tx.Exec(fillChange)
+	if err != nil {
+		return err
+	}
+
+	addConfirmReset := "ALTER TABLE chat_settings ADD COLUMN confirm_reset BOOLEAN NOT NULL DEFAULT false"
+	_, err = tx.Exec(addConfirmReset)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downUndo(tx *sql.Tx) error {
+	dropConfirmReset := "ALTER TABLE chat_settings DROP COLUMN confirm_reset"
+	_, err := tx.Exec(dropConfirmReset)
+	if err != nil {
+		return err
+	}
+
+	dropChange := `
+	ALTER TABLE assignment_events
+	DROP COLUMN change_uuid,
+	DROP COLUMN assignment
+	`
+	_, err = tx.Exec(dropChange)
+	if err != nil {
+		return err
+	}
+	return nil
+}
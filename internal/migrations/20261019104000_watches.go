@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upWatches, downWatches)
+}
+
+func upWatches(tx *sql.Tx) error {
+	createWatches := `
+	CREATE TABLE watches (
+		uuid UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+		chat_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		at DATE NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(chat_id, user_id, at)
+	)
+	`
+	_, err := tx.Exec(createWatches)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downWatches(tx *sql.Tx) error {
+	dropWatches := "DROP TABLE watches"
+	_, err := tx.Exec(dropWatches)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upRedemptions, downRedemptions)
+}
+
+func upRedemptions(tx *sql.Tx) error {
+	createRedemptions := `
+	CREATE TABLE redemptions (
+		uuid UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+		chat_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		days INT NOT NULL CHECK (days > 0),
+		taken_on DATE NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		actor TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+	`
+	_, err := tx.Exec(createRedemptions)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downRedemptions(tx *sql.Tx) error {
+	dropRedemptions := "DROP TABLE redemptions"
+	_, err := tx.Exec(dropRedemptions)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upReportRecipient, downReportRecipient)
+}
+
+func upReportRecipient(tx *sql.Tx) error {
+	addReportUser := "ALTER TABLE chat_settings ADD COLUMN report_user_id BIGINT NOT NULL DEFAULT 0"
+	_, err := tx.Exec(addReportUser)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downReportRecipient(tx *sql.Tx) error {
+	dropReportUser := "ALTER TABLE chat_settings DROP COLUMN report_user_id"
+	_, err := tx.Exec(dropReportUser)
+	if err != nil {
+		return err
+	}
+	return nil
+}
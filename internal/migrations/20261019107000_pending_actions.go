@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upPendingActions, downPendingActions)
+}
+
+func upPendingActions(tx *sql.Tx) error {
+	createPendingActions := `
+	CREATE TABLE pending_actions (
+		uuid UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+		chat_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		kind TEXT NOT NULL,
+		assignments JSONB NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+	`
+	_, err := tx.Exec(createPendingActions)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downPendingActions(tx *sql.Tx) error {
+	dropPendingActions := "DROP TABLE pending_actions"
+	_, err := tx.Exec(dropPendingActions)
+	if err != nil {
+		return err
+	}
+	return nil
+}
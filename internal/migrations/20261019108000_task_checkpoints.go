@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upTaskCheckpoints, downTaskCheckpoints)
+}
+
+func upTaskCheckpoints(tx *sql.Tx) error {
+	createCheckpoints := `
+	CREATE TABLE task_checkpoints (
+		task TEXT PRIMARY KEY,
+		checked_at TIMESTAMP NOT NULL
+	)
+	`
+	_, err := tx.Exec(createCheckpoints)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downTaskCheckpoints(tx *sql.Tx) error {
+	dropCheckpoints := "DROP TABLE task_checkpoints"
+	_, err := tx.Exec(dropCheckpoints)
+	if err != nil {
+		return err
+	}
+	return nil
+}
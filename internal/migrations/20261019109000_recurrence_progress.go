@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upRecurrenceProgress, downRecurrenceProgress)
+}
+
+func upRecurrenceProgress(tx *sql.Tx) error {
+	addMaterializedUntil := "ALTER TABLE recurrences ADD COLUMN materialized_until DATE"
+	_, err := tx.Exec(addMaterializedUntil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downRecurrenceProgress(tx *sql.Tx) error {
+	dropMaterializedUntil := "ALTER TABLE recurrences DROP COLUMN materialized_until"
+	_, err := tx.Exec(dropMaterializedUntil)
+	if err != nil {
+		return err
+	}
+	return nil
+}
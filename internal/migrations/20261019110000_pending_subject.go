@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upPendingSubject, downPendingSubject)
+}
+
+func upPendingSubject(tx *sql.Tx) error {
+	addSubject := "ALTER TABLE pending_actions ADD COLUMN subject_id BIGINT NOT NULL DEFAULT 0"
+	_, err := tx.Exec(addSubject)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downPendingSubject(tx *sql.Tx) error {
+	dropSubject := "ALTER TABLE pending_actions DROP COLUMN subject_id"
+	_, err := tx.Exec(dropSubject)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upEventActorID, downEventActorID)
+}
+
+func upEventActorID(tx *sql.Tx) error {
+	addActorID := "ALTER TABLE assignment_events ADD COLUMN actor_id BIGINT NOT NULL DEFAULT 0"
+	_, err := tx.Exec(addActorID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downEventActorID(tx *sql.Tx) error {
+	dropActorID := "ALTER TABLE assignment_events DROP COLUMN actor_id"
+	_, err := tx.Exec(dropActorID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
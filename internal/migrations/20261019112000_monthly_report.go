@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upMonthlyReport, downMonthlyReport)
+}
+
+func upMonthlyReport(tx *sql.Tx) error {
+	addMonthlyReport := "ALTER TABLE chat_settings ADD COLUMN monthly_report BOOLEAN NOT NULL DEFAULT FALSE"
+	_, err := tx.Exec(addMonthlyReport)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downMonthlyReport(tx *sql.Tx) error {
+	dropMonthlyReport := "ALTER TABLE chat_settings DROP COLUMN monthly_report"
+	_, err := tx.Exec(dropMonthlyReport)
+	if err != nil {
+		return err
+	}
+	return nil
+}
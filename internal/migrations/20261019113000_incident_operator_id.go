@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upIncidentOperatorID, downIncidentOperatorID)
+}
+
+func upIncidentOperatorID(tx *sql.Tx) error {
+	addOperatorID := "ALTER TABLE incidents ADD COLUMN operator_id BIGINT NOT NULL DEFAULT 0"
+	_, err := tx.Exec(addOperatorID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downIncidentOperatorID(tx *sql.Tx) error {
+	dropOperatorID := "ALTER TABLE incidents DROP COLUMN operator_id"
+	_, err := tx.Exec(dropOperatorID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upEventUndo, downEventUndo)
+}
+
+func upEventUndo(tx *sql.Tx) error {
+	addUndo := "ALTER TABLE assignment_events ADD COLUMN undo BOOLEAN NOT NULL DEFAULT FALSE"
+	_, err := tx.Exec(addUndo)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func downEventUndo(tx *sql.Tx) error {
+	dropUndo := "ALTER TABLE assignment_events DROP COLUMN undo"
+	_, err := tx.Exec(dropUndo)
+	if err != nil {
+		return err
+	}
+	return nil
+}
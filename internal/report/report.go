@@ -0,0 +1,134 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Header of report table
+var Header = []string{"operator", "dates", "days", "working days", "weekends", "holidays", "backup days"}
+
+// Report line of operator
+type Row struct {
+	UserID   int64
+	Operator string
+	// Days of primary duty in order
+	Dates []time.Time
+	// Primary duty days by kind of day
+	WorkingDays int
+	Weekends    int
+	Holidays    int
+	// Days operator was a backup
+	Backups int
+}
+
+func (r Row) Days() int {
+	return len(r.Dates)
+}
+
+func (r Row) Record() []string {
+	dates := make([]string, 0, len(r.Dates))
+	for _, date := range r.Dates {
+		dates = append(dates, date.Format(utils.AssignDateFormat))
+	}
+	return []string{
+		r.Operator,
+		strings.Join(dates, " "),
+		strconv.Itoa(r.Days()),
+		strconv.Itoa(r.WorkingDays),
+		strconv.Itoa(r.Weekends),
+		strconv.Itoa(r.Holidays),
+		strconv.Itoa(r.Backups),
+	}
+}
+
+// Build report rows for duty days between from and to inclusive.
+// Weekly duties count every day of the week, several shifts
+// of operator on the same day count once.
+// workingDays must cover the period.
+// Result is sorted by number of days, busiest operators first.
+func Build(
+	assignments []assignment.Assignment,
+	workingDays calendar.TimeSet,
+	from, to time.Time,
+) []Row {
+	byUser := make(map[int64]*Row)
+	primary := make(map[int64]calendar.TimeSet)
+	backup := make(map[int64]calendar.TimeSet)
+	for _, as := range assignments {
+		if as.Operator == "" {
+			continue
+		}
+		row, ok := byUser[as.UserID]
+		if !ok {
+			row = &Row{UserID: as.UserID}
+			byUser[as.UserID] = row
+			primary[as.UserID] = calendar.TimeSet{}
+			backup[as.UserID] = calendar.TimeSet{}
+		}
+		row.Operator = as.Operator
+
+		days := primary[as.UserID]
+		if as.IsBackup() {
+			days = backup[as.UserID]
+		}
+		for _, day := range as.Days() {
+			if day.Before(from) || day.After(to) {
+				continue
+			}
+			days.Add(day)
+		}
+	}
+
+	result := make([]Row, 0, len(byUser))
+	for userID, row := range byUser {
+		row.Backups = len(backup[userID])
+		for day := range primary[userID] {
+			row.Dates = append(row.Dates, day)
+			switch calendar.GetDayType(day, workingDays) {
+			case calendar.WorkingDay:
+				row.WorkingDays++
+			case calendar.Weekend:
+				row.Weekends++
+			case calendar.PublicHoliday:
+				row.Holidays++
+			}
+		}
+		if row.Days() == 0 && row.Backups == 0 {
+			continue
+		}
+		sort.Slice(row.Dates, func(i, j int) bool { return row.Dates[i].Before(row.Dates[j]) })
+		result = append(result, *row)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Days() != result[j].Days() {
+			return result[i].Days() > result[j].Days()
+		}
+		return result[i].Operator < result[j].Operator
+	})
+	return result
+}
+
+// Write rows as CSV with header
+func WriteCSV(w io.Writer, rows []Row) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(Header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := writer.Write(row.Record()); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
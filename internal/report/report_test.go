@@ -0,0 +1,97 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+func date(day int) time.Time {
+	// 2023-01-02 is Monday
+	return time.Date(2023, time.January, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Weekdays of January 2023 except holiday on 2nd
+func workingDays() calendar.TimeSet {
+	days := calendar.TimeSet{}
+	for d := date(1); d.Month() == time.January; d = d.Add(utils.DayDuration) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			days.Add(d)
+		}
+	}
+	days.Remove(date(2))
+	return days
+}
+
+func shift(day, from, to int) assignment.Assignment {
+	return assignment.Assignment{
+		At:       date(day),
+		StartsAt: date(day).Add(time.Duration(from) * time.Hour),
+		EndsAt:   date(day).Add(time.Duration(to) * time.Hour),
+		UserID:   2,
+		Operator: "bob",
+		Role:     assignment.RolePrimary,
+	}
+}
+
+func TestBuild(t *testing.T) {
+	assignments := []assignment.Assignment{
+		{At: date(7), UserID: 1, Operator: "alice", Role: assignment.RolePrimary},
+		{At: date(2), UserID: 1, Operator: "alice", Role: assignment.RolePrimary},
+		{At: date(4), UserID: 1, Operator: "alice", Role: assignment.RolePrimary},
+		{At: date(5), UserID: 1, Operator: "alice", Role: assignment.RoleSecondary},
+		// Two shifts on the same day
+		shift(3, 0, 12),
+		shift(3, 12, 24),
+		{At: date(10), Operator: ""},
+	}
+
+	rows := Build(assignments, workingDays(), date(1), date(31))
+	assert.Len(t, rows, 2)
+
+	alice := rows[0]
+	assert.Equal(t, []time.Time{date(2), date(4), date(7)}, alice.Dates)
+	assert.Equal(t, 1, alice.WorkingDays)
+	assert.Equal(t, 1, alice.Weekends)
+	assert.Equal(t, 1, alice.Holidays)
+	assert.Equal(t, 1, alice.Backups)
+
+	bob := rows[1]
+	assert.Equal(t, 1, bob.Days())
+	assert.Equal(t, 1, bob.WorkingDays)
+}
+
+func TestBuildWeekCrossingPeriod(t *testing.T) {
+	// Week starting on Monday 30th, only two days are in January
+	week := assignment.Assignment{
+		At:       date(30),
+		StartsAt: date(30),
+		EndsAt:   date(30).Add(utils.WeekDuration),
+		UserID:   1,
+		Operator: "alice",
+		Role:     assignment.RolePrimary,
+	}
+
+	rows := Build([]assignment.Assignment{week}, workingDays(), date(1), date(31))
+	assert.Len(t, rows, 1)
+	assert.Equal(t, 2, rows[0].Days())
+}
+
+func TestWriteCSV(t *testing.T) {
+	rows := []Row{{Operator: "alice", Dates: []time.Time{date(2), date(7)}, Weekends: 1, Holidays: 1}}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteCSV(&buf, rows))
+	assert.Equal(
+		t,
+		"operator,dates,days,working days,weekends,holidays,backup days\n"+
+			"alice,02-01-2023 07-01-2023,2,0,1,1,0\n",
+		buf.String(),
+	)
+}
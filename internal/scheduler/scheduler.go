@@ -0,0 +1,299 @@
+// Package scheduler builds fair duty plans.
+//
+// Plan respects operator preferences, absences and duties
+// that are already assigned. Package knows nothing about
+// telegram or database, dates are plain days in UTC.
+//
+// Plan is the best one found by cost: every free day left unfilled
+// costs more than any distribution of duties, then the sum of squared
+// duty counts of operators is minimized, which spreads duties evenly.
+// Search is exhaustive unless it takes more than maxSteps steps,
+// such plans are marked as truncated and might be improvable.
+package scheduler
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	day = 24 * time.Hour
+	// Cost of a day nobody could take
+	unfilledCost = int64(1) << 40
+	// Search stops after this many steps and returns the best plan found
+	maxSteps = 200000
+)
+
+type Preferences struct {
+	// Weekdays operator doesn't take duties on
+	AvoidWeekdays []time.Weekday
+	// Duties per calendar month, 0 means no limit
+	MaxPerMonth int
+	// No duties on two days in a row
+	NoConsecutive bool
+	// No duty on the day after a duty on holiday
+	NoAfterHoliday bool
+}
+
+// Days operator is absent, first and last inclusive
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+type Operator struct {
+	ID          int64
+	Name        string
+	Preferences Preferences
+	Absences    []Period
+}
+
+type Duty struct {
+	OperatorID int64
+	Day        time.Time
+}
+
+type Problem struct {
+	// Free days to plan
+	Days      []time.Time
+	Operators []Operator
+	// Duties already assigned. They are kept and counted for fairness.
+	Duties []Duty
+	// Non working days, used by NoAfterHoliday preference
+	Holidays []time.Time
+}
+
+type Plan struct {
+	// New duties ordered by day
+	Duties []Duty
+	// Days nobody could take
+	Unfilled []time.Time
+	// Duties of every operator including already assigned ones
+	Load map[int64]int
+	// Search was stopped after maxSteps, better plan might exist
+	Truncated bool
+}
+
+type monthKey struct {
+	operator int64
+	year     int
+	month    time.Month
+}
+
+type solver struct {
+	days      []time.Time
+	operators []Operator
+	holidays  map[time.Time]bool
+	avoid     []map[time.Weekday]bool
+
+	// Current partial plan
+	assigned map[time.Time]int64
+	counts   map[int64]int
+	monthly  map[monthKey]int
+	chosen   []int
+	unfilled int
+
+	best     []int
+	bestCost int64
+	steps    int
+}
+
+func date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func newSolver(problem Problem) *solver {
+	s := &solver{
+		operators: problem.Operators,
+		holidays:  make(map[time.Time]bool, len(problem.Holidays)),
+		avoid:     make([]map[time.Weekday]bool, len(problem.Operators)),
+		assigned:  make(map[time.Time]int64),
+		counts:    make(map[int64]int, len(problem.Operators)),
+		monthly:   make(map[monthKey]int),
+		bestCost:  -1,
+	}
+
+	for _, holiday := range problem.Holidays {
+		s.holidays[date(holiday)] = true
+	}
+	for i, op := range problem.Operators {
+		s.avoid[i] = make(map[time.Weekday]bool, len(op.Preferences.AvoidWeekdays))
+		for _, weekday := range op.Preferences.AvoidWeekdays {
+			s.avoid[i][weekday] = true
+		}
+		s.counts[op.ID] = 0
+	}
+	for _, duty := range problem.Duties {
+		s.take(duty.OperatorID, date(duty.Day))
+	}
+
+	seen := make(map[time.Time]bool, len(problem.Days))
+	for _, d := range problem.Days {
+		d = date(d)
+		if _, taken := s.assigned[d]; taken || seen[d] {
+			continue
+		}
+		seen[d] = true
+		s.days = append(s.days, d)
+	}
+	sort.Slice(s.days, func(i, j int) bool { return s.days[i].Before(s.days[j]) })
+	return s
+}
+
+func (s *solver) take(operator int64, d time.Time) {
+	s.assigned[d] = operator
+	s.counts[operator]++
+	s.monthly[monthKey{operator, d.Year(), d.Month()}]++
+}
+
+func (s *solver) release(operator int64, d time.Time) {
+	delete(s.assigned, d)
+	s.counts[operator]--
+	s.monthly[monthKey{operator, d.Year(), d.Month()}]--
+}
+
+func (s *solver) on(d time.Time, operator int64) bool {
+	assigned, ok := s.assigned[d]
+	return ok && assigned == operator
+}
+
+// Operator can take day without breaking any constraint
+func (s *solver) allowed(i int, d time.Time) bool {
+	op := s.operators[i]
+	prefs := op.Preferences
+
+	for _, absence := range op.Absences {
+		if !d.Before(date(absence.From)) && !d.After(date(absence.To)) {
+			return false
+		}
+	}
+	if s.avoid[i][d.Weekday()] {
+		return false
+	}
+	if prefs.MaxPerMonth > 0 && s.monthly[monthKey{op.ID, d.Year(), d.Month()}] >= prefs.MaxPerMonth {
+		return false
+	}
+
+	prev, next := d.Add(-day), d.Add(day)
+	if prefs.NoConsecutive && (s.on(prev, op.ID) || s.on(next, op.ID)) {
+		return false
+	}
+	if prefs.NoAfterHoliday {
+		if s.holidays[prev] && s.on(prev, op.ID) {
+			return false
+		}
+		if s.holidays[d] && s.on(next, op.ID) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *solver) cost() int64 {
+	total := int64(s.unfilled) * unfilledCost
+	for _, count := range s.counts {
+		total += int64(count * count)
+	}
+	return total
+}
+
+// Cost if remaining days went to the least loaded
+// operators ignoring constraints. Never above real cost.
+func (s *solver) lowerBound(remaining int) int64 {
+	if len(s.operators) == 0 {
+		return s.cost() + int64(remaining)*unfilledCost
+	}
+
+	counts := make([]int, 0, len(s.counts))
+	for _, count := range s.counts {
+		counts = append(counts, count)
+	}
+	for ; remaining > 0; remaining-- {
+		least := 0
+		for i := range counts {
+			if counts[i] < counts[least] {
+				least = i
+			}
+		}
+		counts[least]++
+	}
+
+	total := int64(s.unfilled) * unfilledCost
+	for _, count := range counts {
+		total += int64(count * count)
+	}
+	return total
+}
+
+// Operators allowed on day, least loaded first
+func (s *solver) candidates(d time.Time) []int {
+	result := make([]int, 0, len(s.operators))
+	for i := range s.operators {
+		if s.allowed(i, d) {
+			result = append(result, i)
+		}
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		left, right := s.operators[result[a]], s.operators[result[b]]
+		if s.counts[left.ID] != s.counts[right.ID] {
+			return s.counts[left.ID] < s.counts[right.ID]
+		}
+		return left.ID < right.ID
+	})
+	return result
+}
+
+func (s *solver) search(index int) {
+	s.steps++
+	if s.bestCost >= 0 && (s.steps > maxSteps || s.lowerBound(len(s.days)-index) >= s.bestCost) {
+		return
+	}
+	if index == len(s.days) {
+		s.bestCost = s.cost()
+		s.best = append(s.best[:0], s.chosen...)
+		return
+	}
+
+	d := s.days[index]
+	for _, i := range s.candidates(d) {
+		id := s.operators[i].ID
+		s.take(id, d)
+		s.chosen = append(s.chosen, i)
+		s.search(index + 1)
+		s.chosen = s.chosen[:len(s.chosen)-1]
+		s.release(id, d)
+	}
+
+	// Leave the day unfilled
+	s.unfilled++
+	s.chosen = append(s.chosen, -1)
+	s.search(index + 1)
+	s.chosen = s.chosen[:len(s.chosen)-1]
+	s.unfilled--
+}
+
+// Build plan for free days of the problem
+func Solve(problem Problem) Plan {
+	s := newSolver(problem)
+	s.search(0)
+
+	plan := Plan{
+		Load:      make(map[int64]int, len(s.operators)),
+		Truncated: s.steps > maxSteps,
+	}
+	for id, count := range s.counts {
+		plan.Load[id] = count
+	}
+	for index, i := range s.best {
+		d := s.days[index]
+		if i < 0 {
+			plan.Unfilled = append(plan.Unfilled, d)
+			continue
+		}
+		id := s.operators[i].ID
+		plan.Duties = append(plan.Duties, Duty{OperatorID: id, Day: d})
+		plan.Load[id]++
+	}
+	return plan
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// October 2026 starts on Thursday
+func oct(d int) time.Time {
+	return time.Date(2026, time.October, d, 0, 0, 0, 0, time.UTC)
+}
+
+func days(from, to int) []time.Time {
+	result := make([]time.Time, 0, to-from+1)
+	for d := from; d <= to; d++ {
+		result = append(result, oct(d))
+	}
+	return result
+}
+
+func planned(plan Plan) map[time.Time]int64 {
+	result := make(map[time.Time]int64, len(plan.Duties))
+	for _, duty := range plan.Duties {
+		result[duty.Day] = duty.OperatorID
+	}
+	return result
+}
+
+func TestSolveIsFair(t *testing.T) {
+	plan := Solve(Problem{
+		Days:      days(5, 10),
+		Operators: []Operator{{ID: 1}, {ID: 2}, {ID: 3}},
+	})
+
+	assert.Empty(t, plan.Unfilled)
+	assert.Len(t, plan.Duties, 6)
+	assert.Equal(t, map[int64]int{1: 2, 2: 2, 3: 2}, plan.Load)
+	assert.False(t, plan.Truncated)
+}
+
+func TestSolveKeepsExistingDuties(t *testing.T) {
+	plan := Solve(Problem{
+		Days:      days(5, 8),
+		Operators: []Operator{{ID: 1}, {ID: 2}},
+		Duties:    []Duty{{OperatorID: 1, Day: oct(5)}, {OperatorID: 1, Day: oct(6)}},
+	})
+
+	// Already taken days are not planned again
+	assert.Len(t, plan.Duties, 2)
+	assert.Equal(t, map[time.Time]int64{oct(7): 2, oct(8): 2}, planned(plan))
+}
+
+func TestSolveRespectsPreferences(t *testing.T) {
+	plan := Solve(Problem{
+		// Monday to Sunday
+		Days: days(5, 11),
+		Operators: []Operator{
+			{ID: 1, Preferences: Preferences{AvoidWeekdays: []time.Weekday{time.Monday, time.Tuesday}}},
+			{ID: 2, Preferences: Preferences{MaxPerMonth: 2}},
+			{ID: 3, Preferences: Preferences{NoConsecutive: true}},
+		},
+	})
+
+	assert.Empty(t, plan.Unfilled)
+	schedule := planned(plan)
+	assert.NotEqual(t, int64(1), schedule[oct(5)])
+	assert.NotEqual(t, int64(1), schedule[oct(6)])
+	assert.LessOrEqual(t, plan.Load[2], 2)
+	for d := 5; d < 11; d++ {
+		if schedule[oct(d)] == 3 {
+			assert.NotEqual(t, int64(3), schedule[oct(d+1)])
+		}
+	}
+}
+
+func TestSolveNoDutyAfterHoliday(t *testing.T) {
+	plan := Solve(Problem{
+		Days:      []time.Time{oct(12)},
+		Operators: []Operator{{ID: 1, Preferences: Preferences{NoAfterHoliday: true}}, {ID: 2}},
+		Duties:    []Duty{{OperatorID: 1, Day: oct(11)}, {OperatorID: 2, Day: oct(9)}},
+		Holidays:  []time.Time{oct(10), oct(11)},
+	})
+
+	assert.Equal(t, map[time.Time]int64{oct(12): 2}, planned(plan))
+}
+
+func TestSolveRespectsAbsences(t *testing.T) {
+	plan := Solve(Problem{
+		Days: days(5, 9),
+		Operators: []Operator{
+			{ID: 1, Absences: []Period{{From: oct(1), To: oct(7)}}},
+			{ID: 2, Absences: []Period{{From: oct(8), To: oct(20)}}},
+		},
+	})
+
+	assert.Empty(t, plan.Unfilled)
+	assert.Equal(t, map[time.Time]int64{oct(5): 2, oct(6): 2, oct(7): 2, oct(8): 1, oct(9): 1}, planned(plan))
+}
+
+func TestSolveLeavesImpossibleDaysUnfilled(t *testing.T) {
+	plan := Solve(Problem{
+		Days:      days(5, 7),
+		Operators: []Operator{{ID: 1, Preferences: Preferences{MaxPerMonth: 1}}},
+	})
+
+	assert.Len(t, plan.Duties, 1)
+	assert.Len(t, plan.Unfilled, 2)
+
+	plan = Solve(Problem{Days: days(5, 6)})
+	assert.Empty(t, plan.Duties)
+	assert.Equal(t, days(5, 6), plan.Unfilled)
+}
+
+func TestSolvePrefersFilledOverFair(t *testing.T) {
+	// Only operator 1 can take Mondays, so fairness gives way
+	plan := Solve(Problem{
+		Days: []time.Time{oct(5), oct(12), oct(19)},
+		Operators: []Operator{
+			{ID: 1},
+			{ID: 2, Preferences: Preferences{AvoidWeekdays: []time.Weekday{time.Monday}}},
+		},
+	})
+
+	assert.Empty(t, plan.Unfilled)
+	assert.Equal(t, 3, plan.Load[1])
+}
+
+func TestSolveReportsTruncatedSearch(t *testing.T) {
+	// Operators can't fill the month, so bound doesn't prune
+	// and the search runs out of steps
+	operators := make([]Operator, 0, 5)
+	for id := int64(1); id <= 5; id++ {
+		operators = append(operators, Operator{ID: id, Preferences: Preferences{MaxPerMonth: 4}})
+	}
+	plan := Solve(Problem{Days: days(1, 31), Operators: operators})
+
+	assert.True(t, plan.Truncated)
+	assert.Len(t, plan.Duties, 20)
+	assert.Len(t, plan.Unfilled, 11)
+}
@@ -0,0 +1,137 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+// Difference from fair share (in duties) that is worth highlighting
+const FairnessThreshold = 1.0
+
+type OperatorStats struct {
+	UserID   int64
+	Operator string
+	// Duty days in period, a shift counts as its share of the day
+	Duties float64
+	// Days on duty per weekday indexed by time.Weekday
+	Weekdays [utils.DaysInWeek]int
+	// Days on duty right before or after a day off
+	HolidayAdjacent int
+	// Duties of this operator that were reset
+	Resets int
+	// Duties minus fair share. Positive value means
+	// operator took more than others.
+	Fairness float64
+}
+
+// Is operator noticeably over or under fair share
+func (s OperatorStats) IsOver() bool {
+	return s.Fairness >= FairnessThreshold
+}
+
+func (s OperatorStats) IsUnder() bool {
+	return s.Fairness <= -FairnessThreshold
+}
+
+// Share of duty day covered by assignment
+func dayShare(as assignment.Assignment) float64 {
+	if !as.IsShift() {
+		return 1
+	}
+	return float64(as.EndsAt.Sub(as.StartsAt)) / float64(utils.DayDuration)
+}
+
+// Compute per operator statistics for given assignments
+// and events from audit log, only resets are counted of them.
+// Backup duties are not counted. Weekly duties count every day of the week.
+// workingDays must cover a day before the first and
+// a day after the last duty day to detect holiday adjacent duties.
+// Result is sorted by number of duties, most loaded operators first.
+func Compute(
+	assignments []assignment.Assignment,
+	events []assignment.AssignmentEvent,
+	workingDays calendar.TimeSet,
+) []OperatorStats {
+	byUser := make(map[int64]*OperatorStats)
+	daysOnDuty := make(map[int64]calendar.TimeSet)
+	get := func(userID int64, operator string) *OperatorStats {
+		s, ok := byUser[userID]
+		if !ok {
+			s = &OperatorStats{UserID: userID}
+			byUser[userID] = s
+			daysOnDuty[userID] = calendar.TimeSet{}
+		}
+		// Assignments go after events, so the latest name wins
+		s.Operator = operator
+		return s
+	}
+
+	for _, event := range events {
+		if !event.IsReset() {
+			continue
+		}
+		// Operator of events without snapshot is unknown
+		as, err := event.Assignment()
+		if err != nil || as.Operator == "" || as.IsBackup() {
+			continue
+		}
+		get(as.UserID, as.Operator).Resets++
+	}
+
+	for _, as := range assignments {
+		if as.Operator == "" || as.IsBackup() {
+			continue
+		}
+		s := get(as.UserID, as.Operator)
+		share := dayShare(as)
+		for _, date := range as.Days() {
+			s.Duties += share
+			if _, ok := daysOnDuty[as.UserID][date]; ok {
+				continue
+			}
+			daysOnDuty[as.UserID].Add(date)
+			s.Weekdays[date.Weekday()]++
+			if isHolidayAdjacent(date, workingDays) {
+				s.HolidayAdjacent++
+			}
+		}
+	}
+
+	return rank(byUser)
+}
+
+// Compare duties of operators with fair share and sort them
+func rank(byUser map[int64]*OperatorStats) []OperatorStats {
+	result := make([]OperatorStats, 0, len(byUser))
+	total := 0.0
+	for _, s := range byUser {
+		total += s.Duties
+		result = append(result, *s)
+	}
+	if len(result) == 0 {
+		return result
+	}
+
+	share := total / float64(len(result))
+	for i := range result {
+		result[i].Fairness = result[i].Duties - share
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Duties != result[j].Duties {
+			return result[i].Duties > result[j].Duties
+		}
+		return result[i].Operator < result[j].Operator
+	})
+	return result
+}
+
+func isHolidayAdjacent(date time.Time, workingDays calendar.TimeSet) bool {
+	_, prevWorking := workingDays[date.Add(-utils.DayDuration)]
+	_, nextWorking := workingDays[date.Add(utils.DayDuration)]
+	return !prevWorking || !nextWorking
+}
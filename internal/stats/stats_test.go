@@ -0,0 +1,155 @@
+package stats
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FedoseevAlex/DutyBot/internal/calendar"
+	"github.com/FedoseevAlex/DutyBot/internal/database/assignment"
+	"github.com/FedoseevAlex/DutyBot/internal/utils"
+)
+
+func date(day int) time.Time {
+	// 2023-01-02 is Monday
+	return time.Date(2023, time.January, day, 0, 0, 0, 0, time.UTC)
+}
+
+func weekdaysOf(from, to time.Time) calendar.TimeSet {
+	days := calendar.TimeSet{}
+	for d := from; !d.After(to); d = d.Add(utils.DayDuration) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			days.Add(d)
+		}
+	}
+	return days
+}
+
+func duty(day int, userID int64, operator string) assignment.Assignment {
+	return assignment.Assignment{At: date(day), UserID: userID, Operator: operator, Role: assignment.RolePrimary}
+}
+
+func resetEvent(t *testing.T, as assignment.Assignment, undo bool) assignment.AssignmentEvent {
+	snapshot, err := json.Marshal(as)
+	assert.NoError(t, err)
+	return assignment.AssignmentEvent{
+		At:       as.At,
+		Operator: as.Operator,
+		Kind:     assignment.EventDelete,
+		Snapshot: snapshot,
+		Undo:     undo,
+	}
+}
+
+func TestCompute(t *testing.T) {
+	assignments := []assignment.Assignment{
+		duty(2, 1, "alice"),
+		duty(3, 1, "alice"),
+		duty(4, 2, "bob"),
+		duty(6, 1, "alice"),
+		duty(9, 2, "bob"),
+		{At: date(10), Operator: ""},
+	}
+
+	resets := []assignment.AssignmentEvent{
+		resetEvent(t, duty(5, 2, "bob"), false),
+	}
+
+	result := Compute(assignments, resets, weekdaysOf(date(1), date(11)))
+	assert.Len(t, result, 2)
+
+	alice := result[0]
+	assert.Equal(t, "alice", alice.Operator)
+	assert.Equal(t, 3.0, alice.Duties)
+	assert.Equal(t, 1, alice.Weekdays[time.Monday])
+	assert.Equal(t, 1, alice.Weekdays[time.Friday])
+	// Monday after Sunday and Friday before Saturday
+	assert.Equal(t, 2, alice.HolidayAdjacent)
+	assert.Equal(t, 0, alice.Resets)
+	assert.InDelta(t, 0.5, alice.Fairness, 0.001)
+	assert.False(t, alice.IsOver())
+
+	bob := result[1]
+	assert.Equal(t, "bob", bob.Operator)
+	assert.Equal(t, 2.0, bob.Duties)
+	assert.Equal(t, 1, bob.HolidayAdjacent)
+	assert.Equal(t, 1, bob.Resets)
+	assert.InDelta(t, -0.5, bob.Fairness, 0.001)
+	assert.False(t, bob.IsUnder())
+}
+
+func TestComputeByUserID(t *testing.T) {
+	assignments := []assignment.Assignment{
+		// Renamed operator stays one row with the latest name
+		duty(2, 1, "alice"),
+		duty(3, 1, "Alice Smith"),
+		// Namesakes are different operators
+		duty(4, 2, "bob"),
+		duty(5, 3, "bob"),
+	}
+
+	result := Compute(assignments, nil, weekdaysOf(date(1), date(11)))
+	assert.Len(t, result, 3)
+	assert.Equal(t, int64(1), result[0].UserID)
+	assert.Equal(t, "Alice Smith", result[0].Operator)
+	assert.Equal(t, 2.0, result[0].Duties)
+	assert.Equal(t, 1.0, result[1].Duties)
+	assert.Equal(t, 1.0, result[2].Duties)
+}
+
+func TestComputeCountsDutyDays(t *testing.T) {
+	backup := duty(3, 2, "bob")
+	backup.Role = assignment.RoleSecondary
+
+	week := duty(9, 2, "bob")
+	week.StartsAt = date(9)
+	week.EndsAt = date(16)
+
+	shift := func(from, to int) assignment.Assignment {
+		as := duty(4, 1, "alice")
+		as.StartsAt = date(4).Add(time.Duration(from) * time.Hour)
+		as.EndsAt = date(4).Add(time.Duration(to) * time.Hour)
+		return as
+	}
+
+	assignments := []assignment.Assignment{backup, shift(0, 12), shift(12, 18), week}
+	result := Compute(assignments, nil, weekdaysOf(date(1), date(17)))
+	assert.Len(t, result, 2)
+
+	bob := result[0]
+	assert.Equal(t, 7.0, bob.Duties)
+	assert.Equal(t, 1, bob.Weekdays[time.Monday])
+	assert.Equal(t, 1, bob.Weekdays[time.Sunday])
+	// Friday before weekend, weekend days and Monday after it
+	assert.Equal(t, 4, bob.HolidayAdjacent)
+
+	alice := result[1]
+	assert.InDelta(t, 0.75, alice.Duties, 0.001)
+	// Shifts of the same day are one day on duty
+	assert.Equal(t, 1, alice.Weekdays[time.Wednesday])
+}
+
+func TestComputeCountsOnlyResets(t *testing.T) {
+	as := duty(4, 1, "alice")
+	swap := resetEvent(t, as, false)
+	swap.Kind = assignment.EventSwap
+
+	events := []assignment.AssignmentEvent{
+		resetEvent(t, as, false),
+		// Undo of assignment
+		resetEvent(t, as, true),
+		swap,
+		// Events without snapshot have no operator ID
+		{At: as.At, Operator: as.Operator, Kind: assignment.EventDelete},
+	}
+
+	result := Compute(nil, events, weekdaysOf(date(1), date(11)))
+	assert.Len(t, result, 1)
+	assert.Equal(t, 1, result[0].Resets)
+}
+
+func TestComputeEmpty(t *testing.T) {
+	assert.Empty(t, Compute(nil, nil, calendar.TimeSet{}))
+}
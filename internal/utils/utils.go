@@ -2,8 +2,11 @@ package utils
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -25,6 +28,15 @@ const (
 	defaultPadchar   byte          = ' '
 )
 
+// Separator of first and last days in date ranges
+const DateRangeSeparator = ".."
+
+// Day, month and year separated by anything, e.g. DD-MM-YYYY
+var dateRegexp = regexp.MustCompile("([0-9]{1,2}).*?([0-9]{1,2}).*?([0-9]{4})")
+
+// ISO week like 2026-W43 or W43 for current year
+var isoWeekRegexp = regexp.MustCompile(`(?i)^(?:([0-9]{4})-?)?W([0-9]{1,2})$`)
+
 type PrettyTable struct {
 	rows     [][]string
 	Minwidth int
@@ -81,6 +93,15 @@ func GetToday() time.Time {
 	return today
 }
 
+// Current wall clock time labeled as UTC.
+// Matches dates returned by GetToday so shifts
+// can be compared with days.
+func GetNow() time.Time {
+	now := time.Now()
+	y, m, d := now.Date()
+	return time.Date(y, m, d, now.Hour(), now.Minute(), now.Second(), 0, time.UTC)
+}
+
 // This function returns time.Time object
 // representing tomorrow date.
 func GetTomorrow() time.Time {
@@ -120,3 +141,65 @@ func GetStartOfWeek(t time.Time) time.Time {
 	}
 	return t
 }
+
+// Monday of ISO 8601 week of the year
+func GetStartOfISOWeek(year int, week int) time.Time {
+	// January 4th always belongs to the first week
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	return GetStartOfWeek(jan4).AddDate(0, 0, (week-1)*DaysInWeek)
+}
+
+// Parse ISO week into its monday.
+// Returns false if argument doesn't look like a week.
+func ParseISOWeek(argument string) (time.Time, bool, error) {
+	match := isoWeekRegexp.FindStringSubmatch(argument)
+	if match == nil {
+		return time.Time{}, false, nil
+	}
+
+	year, _ := GetToday().ISOWeek()
+	if match[1] != "" {
+		year, _ = strconv.Atoi(match[1])
+	}
+	week, _ := strconv.Atoi(match[2])
+
+	monday := GetStartOfISOWeek(year, week)
+	if y, w := monday.ISOWeek(); y != year || w != week {
+		return time.Time{}, true, fmt.Errorf("there is no week %d in %d", week, year)
+	}
+	return monday, true, nil
+}
+
+// Parse date like DD-MM-YYYY
+func ParseDate(probablyTime string) (time.Time, error) {
+	if !dateRegexp.MatchString(probablyTime) {
+		return time.Time{}, fmt.Errorf("'%s' is not look like DD MM YYYY", probablyTime)
+	}
+	parts := dateRegexp.FindAllStringSubmatch(probablyTime, 1)[0]
+	date := fmt.Sprintf("%02s", parts[1])
+	month := fmt.Sprintf("%02s", parts[2])
+	year := parts[3]
+	t, err := time.Parse(DateFormat, fmt.Sprintf("%s-%s-%s", year, month, date))
+	return t, err
+}
+
+// Parse DD-MM-YYYY..DD-MM-YYYY or single DD-MM-YYYY
+func ParseDateRange(raw string) (time.Time, time.Time, error) {
+	first, last, ok := strings.Cut(raw, DateRangeSeparator)
+	if !ok {
+		last = first
+	}
+
+	from, err := ParseDate(first)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := ParseDate(last)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("range should end after it starts")
+	}
+	return from, to, nil
+}
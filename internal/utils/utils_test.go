@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestGetStartOfISOWeek(t *testing.T) {
+	cases := []struct {
+		year, week int
+		monday     time.Time
+	}{
+		{2026, 43, date(2026, time.October, 19)},
+		// First week starts in previous year
+		{2026, 1, date(2025, time.December, 29)},
+		{2021, 1, date(2021, time.January, 4)},
+		{2020, 1, date(2019, time.December, 30)},
+		{2020, 53, date(2020, time.December, 28)},
+		{2015, 53, date(2015, time.December, 28)},
+	}
+	for _, c := range cases {
+		monday := GetStartOfISOWeek(c.year, c.week)
+		assert.Equal(t, c.monday, monday)
+		year, week := monday.ISOWeek()
+		assert.Equal(t, c.year, year)
+		assert.Equal(t, c.week, week)
+	}
+}
+
+func TestParseISOWeek(t *testing.T) {
+	cases := []struct {
+		argument string
+		monday   time.Time
+	}{
+		{"2026-W43", date(2026, time.October, 19)},
+		{"2026W43", date(2026, time.October, 19)},
+		{"2026-w1", date(2025, time.December, 29)},
+		{"2020-W53", date(2020, time.December, 28)},
+	}
+	for _, c := range cases {
+		monday, ok, err := ParseISOWeek(c.argument)
+		assert.NoError(t, err, c.argument)
+		assert.True(t, ok, c.argument)
+		assert.Equal(t, c.monday, monday, c.argument)
+	}
+
+	// Current year by default
+	monday, ok, err := ParseISOWeek("W10")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	year, _ := GetToday().ISOWeek()
+	assert.Equal(t, GetStartOfISOWeek(year, 10), monday)
+
+	for _, argument := range []string{"2021-W53", "2026-W00", "2026-W60"} {
+		_, ok, err = ParseISOWeek(argument)
+		assert.True(t, ok, argument)
+		assert.Error(t, err, argument)
+	}
+
+	for _, argument := range []string{"", "20-10-2026", "W", "2026-43", "week 43"} {
+		_, ok, err = ParseISOWeek(argument)
+		assert.False(t, ok, argument)
+		assert.NoError(t, err, argument)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	cases := []struct {
+		argument string
+		day      time.Time
+	}{
+		{"20-10-2026", date(2026, time.October, 20)},
+		{"1.2.2026", date(2026, time.February, 1)},
+		{"20 10 2026", date(2026, time.October, 20)},
+	}
+	for _, c := range cases {
+		day, err := ParseDate(c.argument)
+		assert.NoError(t, err, c.argument)
+		assert.Equal(t, c.day, day, c.argument)
+	}
+
+	for _, argument := range []string{"", "20-10", "tomorrow", "31-02-2026"} {
+		_, err := ParseDate(argument)
+		assert.Error(t, err, argument)
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	from, to, err := ParseDateRange("20-10-2026..25-10-2026")
+	assert.NoError(t, err)
+	assert.Equal(t, date(2026, time.October, 20), from)
+	assert.Equal(t, date(2026, time.October, 25), to)
+
+	// Single day is a range of one day
+	from, to, err = ParseDateRange("20-10-2026")
+	assert.NoError(t, err)
+	assert.Equal(t, date(2026, time.October, 20), from)
+	assert.Equal(t, from, to)
+
+	for _, argument := range []string{"25-10-2026..20-10-2026", "20-10-2026..", "..20-10-2026"} {
+		_, _, err = ParseDateRange(argument)
+		assert.Error(t, err, argument)
+	}
+}